@@ -0,0 +1,204 @@
+package gofmt256
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	aggCount = "count"
+	aggSum   = "sum"
+)
+
+// AggregateMismatchError is returned by the parser when an aggregate field of
+// a footer or batch trailer does not match the records it summarises.
+type AggregateMismatchError struct {
+	Field string
+	Agg   string
+	Want  int64
+	Got   int64
+}
+
+func (e *AggregateMismatchError) Error() string {
+	return fmt.Sprintf("[%s] %s mismatch, records give %d but got %d", e.Field, e.Agg, e.Want, e.Got)
+}
+
+func (e *AggregateMismatchError) Is(target error) bool {
+	return target == ErrAggregateMismatch
+}
+
+// extractAggregate reads the value of an `agg` subtag, which is either
+// `count` or `sum(Field)`.
+func extractAggregate(value string) (agg string, of string, err error) {
+	if value == aggCount {
+		return aggCount, "", nil
+	}
+	if strings.HasPrefix(value, aggSum+"(") && strings.HasSuffix(value, ")") {
+		of = strings.TrimSuffix(strings.TrimPrefix(value, aggSum+"("), ")")
+		if of != "" {
+			return aggSum, of, nil
+		}
+	}
+	return "", "", errors.Errorf("`agg` must be `count` or `sum(Field)`, got `%s`", value)
+}
+
+// tally computes the aggregate fields of a footer or batch trailer type over
+// the records added to it.
+type tally struct {
+	fields []FieldStruct
+	totals map[string]int64
+	opts   options
+}
+
+func newTally(input reflect.Type, opts options) (*tally, error) {
+	t := &tally{
+		totals: make(map[string]int64),
+		opts:   opts,
+	}
+	if !hasRecordLayout(input) {
+		return t, nil
+	}
+
+	schema, err := compile(input, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, fs := range schema.fields {
+		if fs.agg != "" {
+			t.fields = append(t.fields, fs)
+			t.totals[fs.Name] = 0
+		}
+	}
+	return t, nil
+}
+
+func (t *tally) reset() {
+	for name := range t.totals {
+		t.totals[name] = 0
+	}
+}
+
+func (t *tally) add(record reflect.Value) error {
+	if len(t.fields) == 0 {
+		return nil
+	}
+	record, err := recordValue(record, t.opts)
+	if err != nil {
+		return err
+	}
+	if !hasRecordLayout(record.Type()) {
+		return t.addMarshaled(record.Type())
+	}
+
+	schema, err := compile(record.Type(), t.opts)
+	if err != nil {
+		return err
+	}
+	byName := schema.byName
+
+	for _, agg := range t.fields {
+		if agg.where != "" {
+			fs, ok := byName[agg.where]
+			if !ok {
+				return errors.Errorf("[%s] where field `%s` is not in %s", agg.Name, agg.where, record.Type())
+			}
+			data, err := fieldData(fs, record.Field(fs.index), t.opts)
+			if err != nil {
+				return err
+			}
+			if data != agg.equals {
+				continue
+			}
+		}
+
+		switch agg.agg {
+		case aggCount:
+			t.totals[agg.Name]++
+		case aggSum:
+			fs, ok := byName[agg.aggOf]
+			if !ok {
+				return errors.Errorf("[%s] sum field `%s` is not in %s", agg.Name, agg.aggOf, record.Type())
+			}
+			data, err := fieldData(fs, record.Field(fs.index), t.opts)
+			if err != nil {
+				return err
+			}
+			n, err := parseTotal(data)
+			if err != nil {
+				return fieldError(fs, data, ErrInvalidValue, errors.Wrap(err, "unable to sum"))
+			}
+			t.totals[agg.Name] += n
+		}
+	}
+	return nil
+}
+
+// addMarshaled counts a record of type rt that marshals its own line, which
+// has no fields to filter or sum.
+func (t *tally) addMarshaled(rt reflect.Type) error {
+	for _, agg := range t.fields {
+		if agg.where != "" {
+			return errors.Errorf("[%s] where field `%s` is not in %s, which marshals its own line", agg.Name, agg.where, rt)
+		}
+		if agg.agg == aggSum {
+			return errors.Errorf("[%s] sum field `%s` is not in %s, which marshals its own line", agg.Name, agg.aggOf, rt)
+		}
+	}
+	for _, agg := range t.fields {
+		t.totals[agg.Name]++
+	}
+	return nil
+}
+
+// verify compares the aggregate fields of a parsed footer or batch trailer
+// with the totals of the records added to t.
+func (t *tally) verify(input reflect.Value) error {
+	input, err := recordValue(input, t.opts)
+	if err != nil {
+		return err
+	}
+	if !hasRecordLayout(input.Type()) {
+		return nil
+	}
+	for _, agg := range t.fields {
+		data, err := fieldData(agg, input.Field(agg.index), t.opts)
+		if err != nil {
+			return err
+		}
+		got, err := parseTotal(data)
+		if err != nil {
+			return fieldError(agg, data, ErrInvalidValue, errors.Wrap(err, "unable to read aggregate"))
+		}
+		if want := t.totals[agg.Name]; got != want {
+			name := agg.agg
+			if agg.agg == aggSum {
+				name = aggSum + "(" + agg.aggOf + ")"
+			}
+			err := fieldError(agg, data, ErrAggregateMismatch, &AggregateMismatchError{
+				Field: agg.Name,
+				Agg:   name,
+				Want:  want,
+				Got:   got,
+			})
+			err.Reason = fmt.Sprintf("%s mismatch, records give %d but got %d", name, want, got)
+			return err
+		}
+	}
+	return nil
+}
+
+func parseTotal(data string) (int64, error) {
+	data = strings.TrimSpace(data)
+	if data == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(data, 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "`%s` is not a number", data)
+	}
+	return n, nil
+}
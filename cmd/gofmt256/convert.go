@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/100x-fi/gofmt256"
+	"github.com/pkg/errors"
+)
+
+// converter reads or writes a section of a file in another format.
+type converter struct {
+	read  func(l *gofmt256.FileLayout, r io.Reader, f *gofmt256.LayoutFile, section string) error
+	write func(l *gofmt256.FileLayout, w io.Writer, f *gofmt256.LayoutFile, section string) error
+}
+
+var converters = map[string]converter{
+	"csv":  {read: (*gofmt256.FileLayout).ReadCSV, write: (*gofmt256.FileLayout).WriteCSV},
+	"json": {read: (*gofmt256.FileLayout).ReadJSON, write: (*gofmt256.FileLayout).WriteJSON},
+}
+
+// convertFlags are the flags of the conversion commands. When header and
+// footer are set, the header and footer records are in files of their own
+// and the main input or output only has the body.
+type convertFlags struct {
+	layoutFlags
+	output string
+	header string
+	footer string
+}
+
+func parseConvertFlags(name string, args []string) (*flag.FlagSet, *convertFlags, error) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	var cf convertFlags
+	cf.register(flags)
+	flags.StringVar(&cf.output, "o", "", "output file (default stdout)")
+	flags.StringVar(&cf.header, "header", "", "file of the header record, apart from the body")
+	flags.StringVar(&cf.footer, "footer", "", "file of the footer record, apart from the body")
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	if (cf.header == "") != (cf.footer == "") {
+		return nil, nil, errors.New("--header and --footer must be given together")
+	}
+	return flags, &cf, nil
+}
+
+// section returns the section of the main input or output.
+func (cf *convertFlags) section() string {
+	if cf.header != "" {
+		return gofmt256.SectionBody
+	}
+	return ""
+}
+
+func (cf *convertFlags) writeOutput(stdout io.Writer, data []byte) error {
+	if cf.output == "" {
+		_, err := stdout.Write(data)
+		return err
+	}
+	return ioutil.WriteFile(cf.output, data, 0644)
+}
+
+// toFormat converts a fixed-width file to format.
+func toFormat(format string, args []string, stdin io.Reader, stdout io.Writer) error {
+	flags, cf, err := parseConvertFlags("to-"+format, args)
+	if err != nil {
+		return err
+	}
+	layout, err := cf.load()
+	if err != nil {
+		return err
+	}
+	data, err := readInput(flags, stdin)
+	if err != nil {
+		return err
+	}
+	f, err := layout.Parse(data)
+	if err != nil {
+		return err
+	}
+
+	write := converters[format].write
+	if cf.header != "" {
+		for section, path := range map[string]string{gofmt256.SectionHeader: cf.header, gofmt256.SectionFooter: cf.footer} {
+			var buf bytes.Buffer
+			if err := write(layout, &buf, f, section); err != nil {
+				return err
+			}
+			if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+				return err
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := write(layout, &buf, f, cf.section()); err != nil {
+		return err
+	}
+	return cf.writeOutput(stdout, buf.Bytes())
+}
+
+// fromFormat converts a file in format to a fixed-width file.
+func fromFormat(format string, args []string, stdin io.Reader, stdout io.Writer) error {
+	flags, cf, err := parseConvertFlags("from-"+format, args)
+	if err != nil {
+		return err
+	}
+	layout, err := cf.load()
+	if err != nil {
+		return err
+	}
+	data, err := readInput(flags, stdin)
+	if err != nil {
+		return err
+	}
+
+	c := converters[format]
+	var f gofmt256.LayoutFile
+	if err := c.read(layout, bytes.NewReader(data), &f, cf.section()); err != nil {
+		return err
+	}
+	if cf.header != "" {
+		for section, path := range map[string]string{gofmt256.SectionHeader: cf.header, gofmt256.SectionFooter: cf.footer} {
+			if err := readSection(layout, c, path, &f, section); err != nil {
+				return err
+			}
+		}
+	}
+
+	out, err := layout.Build(&f)
+	if err != nil {
+		return err
+	}
+	return cf.writeOutput(stdout, []byte(out))
+}
+
+func readSection(layout *gofmt256.FileLayout, c converter, path string, f *gofmt256.LayoutFile, section string) error {
+	r, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return errors.Wrap(c.read(layout, r, f, section), path)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/100x-fi/gofmt256"
+)
+
+const (
+	colorRed   = "\x1b[31m"
+	colorReset = "\x1b[0m"
+)
+
+func inspect(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	var lf layoutFlags
+	lf.register(flags)
+	only := flags.Int("line", 0, "only inspect this line")
+	field := flags.String("field", "", "only show this field")
+	color := flags.Bool("color", false, "highlight invalid fields in red")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	layout, err := lf.load()
+	if err != nil {
+		return err
+	}
+	data, err := readInput(flags, stdin)
+	if err != nil {
+		return err
+	}
+
+	length, unit := recordLength(layout), widthUnit(layout)
+	lines, err := splitLines(layout, data)
+	if err != nil {
+		return err
+	}
+	for i, line := range lines {
+		n, text := i+1, line.text
+		if *only != 0 && n != *only {
+			continue
+		}
+
+		section, l, err := layout.RecordLayout(n, len(lines), line.recordType)
+		if err != nil {
+			fmt.Fprintf(stdout, "line %d, offset %d: %s\n\n", n, line.offset, err)
+			continue
+		}
+		schema, err := gofmt256.Compile(l, layout.Options()...)
+		if err != nil {
+			return err
+		}
+		var values []gofmt256.FieldValue
+		for _, fv := range schema.Inspect(text, layout.Options()...) {
+			if *field == "" || fv.Name == *field {
+				values = append(values, fv)
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(stdout, "line %d, offset %d: %s", n, line.offset, section)
+		if l.Name != "" {
+			fmt.Fprintf(stdout, " (%s)", l.Name)
+		}
+		fmt.Fprintln(stdout)
+		if line.width != length {
+			fmt.Fprintf(stdout, "! line is %d %s long, expected %d\n", line.width, unit, length)
+		}
+		if line.err != nil {
+			fmt.Fprintf(stdout, "! %s\n", line.err)
+		}
+
+		w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "  NAME\tFROM-TO\tRAW\tTRIMMED\tPROBLEM")
+		for _, fv := range values {
+			mark, start, end := " ", "", ""
+			if fv.Problem != "" {
+				mark = "!"
+				if *color {
+					start, end = colorRed, colorReset
+				}
+			}
+			fmt.Fprintf(w, "%s%s %s\t%d-%d\t|%s|\t%s\t%s%s\n", start, mark, fv.Name, fv.From, fv.To, fv.Raw, fv.Trimmed, fv.Problem, end)
+		}
+		w.Flush()
+		fmt.Fprintln(stdout)
+	}
+	return nil
+}
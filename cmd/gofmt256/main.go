@@ -0,0 +1,178 @@
+// Command gofmt256 works with fixed-width files described by a layout.
+//
+// Usage:
+//
+//	gofmt256 inspect --layout layout.yaml [--line N] [--field Name] [--color] file.txt
+//	gofmt256 validate --layout layout.yaml file.txt
+//	gofmt256 to-csv|to-json --layout layout.yaml [-o out] [--header h --footer f] file.txt
+//	gofmt256 from-csv|from-json --layout layout.yaml [-o file.txt] [--header h --footer f] in
+//
+// Validate prints a JSON report of the lines that do not match the layout
+// and of sequence gaps and control totals that do not match, and exits with
+// status 1 when there are any.
+//
+// The conversion commands write every record to one output, naming the
+// layout of each CSV row in a record column, or with --header and --footer
+// the header and footer records to files of their own.
+//
+// A layout is a JSON or YAML FileLayout, a single JSON or YAML Layout used for
+// every line, or Go source with structs tagged for gofmt256. In Go source, a
+// struct named ...Header is the header, one named ...Footer or ...Trailer the
+// footer, and the others are body records, of which --body picks one.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/100x-fi/gofmt256"
+	"github.com/100x-fi/gofmt256/internal/golayout"
+	"github.com/pkg/errors"
+)
+
+const usage = `usage: gofmt256 <command> [flags] [file]
+
+commands:
+  inspect   print the fields of every line of a file
+  validate  check a file against its layout and print a JSON report
+  to-csv    convert a file to CSV
+  to-json   convert a file to JSON
+  from-csv  convert CSV to a file
+  from-json convert JSON to a file
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "inspect":
+		err = inspect(args[1:], stdin, stdout)
+	case "validate":
+		err = validate(args[1:], stdin, stdout)
+	case "to-csv", "to-json":
+		err = toFormat(strings.TrimPrefix(args[0], "to-"), args[1:], stdin, stdout)
+	case "from-csv", "from-json":
+		err = fromFormat(strings.TrimPrefix(args[0], "from-"), args[1:], stdin, stdout)
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "gofmt256: unknown command %q\n%s", args[0], usage)
+		return 2
+	}
+	if err == flag.ErrHelp {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "gofmt256:", err)
+		return 1
+	}
+	return 0
+}
+
+// layoutFlags are the flags of every command that reads a layout.
+type layoutFlags struct {
+	layout string
+	body   string
+}
+
+func (f *layoutFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&f.layout, "layout", "", "layout file (.yaml, .json or .go) or Go package directory")
+	flags.StringVar(&f.body, "body", "", "body struct to use when Go source has more than one")
+}
+
+func (f *layoutFlags) load() (*gofmt256.FileLayout, error) {
+	if f.layout == "" {
+		return nil, errors.New("--layout is required")
+	}
+
+	fi, err := os.Stat(f.layout)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() && filepath.Ext(f.layout) != ".go" {
+		return gofmt256.LoadFileLayout(f.layout)
+	}
+
+	_, structs, err := golayout.Parse(f.layout)
+	if err != nil {
+		return nil, err
+	}
+	if f.body != "" {
+		var selected []golayout.Struct
+		for _, s := range structs {
+			if s.Name == f.body || !isBody(s.Name) {
+				selected = append(selected, s)
+			}
+		}
+		structs = selected
+	}
+	return golayout.FileLayout(structs)
+}
+
+func isBody(name string) bool {
+	return !strings.HasSuffix(name, "Header") && !strings.HasSuffix(name, "Footer") && !strings.HasSuffix(name, "Trailer")
+}
+
+// readInput reads the file named by the only argument of flags, or stdin
+// when there is none.
+func readInput(flags *flag.FlagSet, stdin io.Reader) ([]byte, error) {
+	switch flags.NArg() {
+	case 0:
+		return ioutil.ReadAll(stdin)
+	case 1:
+		return ioutil.ReadFile(flags.Arg(0))
+	}
+	return nil, errors.New("only one file can be given")
+}
+
+// line is a line of a file, decoded from the encoding of its layout.
+type line struct {
+	text       string
+	offset     int64
+	width      int
+	recordType string
+	err        error // bytes of the line that the encoding does not define
+}
+
+// splitLines reads the lines of data with a Decoder set up by layout.
+func splitLines(layout *gofmt256.FileLayout, data []byte) ([]line, error) {
+	d := gofmt256.NewDecoder(bytes.NewReader(data), layout.Options()...)
+	var lines []line
+	for d.Next() {
+		l := line{offset: d.Offset(), width: d.Width(), recordType: d.RecordType()}
+		l.text, l.err = d.Text()
+		lines = append(lines, l)
+	}
+	return lines, d.Err()
+}
+
+// recordLength returns the record length of l.
+func recordLength(l *gofmt256.FileLayout) int {
+	if l.Length == 0 {
+		return 256
+	}
+	return l.Length
+}
+
+// widthUnit returns the width unit of l.
+func widthUnit(l *gofmt256.FileLayout) gofmt256.WidthUnit {
+	if l.WidthUnit == "" {
+		return gofmt256.WidthBytes
+	}
+	return l.WidthUnit
+}
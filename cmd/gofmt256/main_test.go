@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const layoutYAML = `length: 20
+header:
+  name: header
+  record_type: H
+  fields:
+    - {name: type, from: 1, to: 1}
+    - {name: date, from: 2, to: 9}
+    - {name: spare, from: 10, to: 20}
+body:
+  - name: detail
+    record_type: D
+    fields:
+      - {name: type, from: 1, to: 1}
+      - {name: account, from: 2, to: 9}
+      - {name: amount, from: 10, to: 20, align: R, padding: "0", type: decimal, decimals: 2}
+footer:
+  name: trailer
+  record_type: T
+  fields:
+    - {name: type, from: 1, to: 1}
+    - {name: count, from: 2, to: 7, align: R, padding: "0", type: int, agg: count}
+    - {name: spare, from: 8, to: 20}
+`
+
+const file = "H20200102           \n" +
+	"D12345678  00001000\n" +
+	"D8765432100000002000\n" +
+	"T000002             \n"
+
+func writeTemp(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestInspect(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gofmt256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	layout := writeTemp(t, dir, "layout.yaml", layoutYAML)
+	data := writeTemp(t, dir, "data.txt", file)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"inspect", "--layout", layout, "--line", "2", data}, nil, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, "line 2, offset 21: body (detail)\n"+
+		"! line is 19 bytes long, expected 20\n"+
+		"  NAME     FROM-TO  RAW           TRIMMED   PROBLEM\n"+
+		"  type     1-1      |D|           D         \n"+
+		"  account  2-9      |12345678|    12345678  \n"+
+		"! amount   10-20    |  00001000|            line is too short\n"+
+		"\n", stdout.String())
+
+	stdout.Reset()
+	code = run([]string{"inspect", "--layout", layout, "--field", "amount", data}, nil, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "line 3, offset 41: body (detail)\n")
+	assert.Contains(t, stdout.String(), "  amount  10-20    |00000002000|  2000     \n")
+	assert.Equal(t, 2, strings.Count(stdout.String(), "amount"))
+
+	stdout.Reset()
+	code = run([]string{"inspect", "--layout", layout, "--line", "4", "--color"}, strings.NewReader(file), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "line 4, offset 62: footer (trailer)\n")
+	assert.NotContains(t, stdout.String(), colorRed)
+}
+
+func TestInspectGoLayout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gofmt256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeTemp(t, dir, "records.go", "package bank\n\n"+
+		"type FileHeader struct {\n"+
+		"\tType string `gofmt256:\"from=1,to=1\"`\n"+
+		"\tSpare string `gofmt256:\"from=2,to=10\"`\n"+
+		"}\n\n"+
+		"type Detail struct {\n"+
+		"\tType string `gofmt256:\"from=1,to=1\"`\n"+
+		"\tAmount int `gofmt256:\"from=2,to=10,align=R,padding='0'\"`\n"+
+		"}\n\n"+
+		"type FileTrailer struct {\n"+
+		"\tType string `gofmt256:\"from=1,to=1\"`\n"+
+		"\tSpare string `gofmt256:\"from=2,to=10\"`\n"+
+		"}\n")
+	input := "H         \nD    12   \nT         \n"
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"inspect", "--layout", dir, "--line", "2", "--color"}, strings.NewReader(input), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "line 2, offset 11: body (Detail)\n")
+	assert.Contains(t, stdout.String(), colorRed+"! Amount")
+	assert.Contains(t, stdout.String(), "value is not aligned to the right"+colorReset)
+}
+
+func TestValidate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gofmt256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	layout := writeTemp(t, dir, "layout.yaml", layoutYAML)
+	valid := "H20200102           \n" +
+		"D1234567800000001000\n" +
+		"D8765432100000002000\n" +
+		"T000002             \n"
+	data := writeTemp(t, dir, "data.txt", valid)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"validate", "--layout", layout, data}, nil, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, `{
+  "file": "`+data+`",
+  "valid": true,
+  "lines": 4,
+  "errors": []
+}
+`, stdout.String())
+
+	tests := []struct {
+		name  string
+		input string
+		want  []issue
+	}{
+		{
+			name:  "line length",
+			input: "H20200102           \nD12345678  00001000\nD87654321 0000002000\nT000002             \n",
+			want: []issue{
+				{Check: checkLength, Line: 2, Offset: 21, Value: "D12345678  00001000", Reason: "line is 19 bytes long, expected 20"},
+				{Check: checkPadding, Line: 3, Offset: 41, Section: "body", Record: "detail", Field: "amount", From: 10, To: 20, Value: " 0000002000", Reason: "value is padded with spaces instead of `0`"},
+			},
+		},
+		{
+			name:  "order",
+			input: "D1234567800000001000\nH20200102           \nT000002             \n",
+			want: []issue{
+				{Check: checkOrder, Line: 1, Offset: 0, Section: "header", Record: "header", Value: "D", Reason: "header must have record type `H`"},
+				{Check: checkOrder, Line: 2, Offset: 21, Section: "body", Record: "detail", Value: "H", Reason: "body must have record type `D`"},
+			},
+		},
+		{
+			name:  "numeric",
+			input: "H20200102           \nD123456780000000100x\nT000001             \n",
+			want: []issue{
+				{Check: checkValue, Line: 2, Offset: 21, Section: "body", Record: "detail", Field: "amount", From: 10, To: 20, Value: "0000000100x", Reason: "amount `100x` is not a number"},
+			},
+		},
+		{
+			name:  "control totals",
+			input: strings.Replace(valid, "T000002", "T000003", 1),
+			want: []issue{
+				{Check: checkTotals, Line: 4, Offset: 63, Section: "footer", Record: "trailer", Field: "count", From: 2, To: 7, Value: "3", Reason: "count mismatch, records give 2 but got 3"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run([]string{"validate", "--layout", layout}, strings.NewReader(tt.input), &stdout, &stderr)
+			assert.Equal(t, 1, code)
+			assert.Equal(t, "gofmt256: file is invalid\n", stderr.String())
+			var r report
+			assert.NoError(t, json.Unmarshal(stdout.Bytes(), &r))
+			assert.False(t, r.Valid)
+			assert.Equal(t, tt.want, r.Errors)
+		})
+	}
+}
+
+func TestValidateEncoding(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gofmt256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	layout := writeTemp(t, dir, "layout.yaml", "encoding: tis-620\n"+layoutYAML)
+
+	// the account is read as TIS-620, one byte for each Thai character
+	valid := "H20200102           \n" +
+		"D\xca\xc1\xc8\xd1\xa1\xb4\xd4\xec00000001000\n" +
+		"T000001             \n"
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"validate", "--layout", layout}, strings.NewReader(valid), &stdout, &stderr)
+	assert.Equal(t, 0, code, stdout.String())
+
+	stdout.Reset()
+	code = run([]string{"validate", "--layout", layout}, strings.NewReader(strings.Replace(valid, "\xec", "\xff", 1)), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	var r report
+	assert.NoError(t, json.Unmarshal(stdout.Bytes(), &r))
+	assert.Equal(t, []issue{{Check: checkEncoding, Line: 2, Offset: 21, Reason: "byte 0xFF at 8 is not defined in TIS-620"}}, r.Errors)
+}
+
+func TestValidateLineEnding(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gofmt256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	valid := strings.Replace(file, "D12345678  00001000", "D1234567800000001000", 1)
+
+	tests := []struct {
+		ending string
+		input  string
+	}{
+		{ending: "crlf", input: strings.Replace(valid, "\n", "\r\n", -1)},
+		{ending: "none", input: strings.Replace(valid, "\n", "", -1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ending, func(t *testing.T) {
+			layout := writeTemp(t, dir, tt.ending+".yaml", "line_ending: "+tt.ending+"\n"+layoutYAML)
+			var stdout, stderr bytes.Buffer
+			code := run([]string{"validate", "--layout", layout}, strings.NewReader(tt.input), &stdout, &stderr)
+			assert.Equal(t, 0, code, stdout.String())
+			var r report
+			assert.NoError(t, json.Unmarshal(stdout.Bytes(), &r))
+			assert.Equal(t, 4, r.Lines)
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gofmt256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	layout := writeTemp(t, dir, "layout.yaml", layoutYAML)
+	valid := "H20200102           \n" +
+		"D1234567800000001000\n" +
+		"D8765432100000002000\n" +
+		"T000002             \n"
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"to-csv", "--layout", layout}, strings.NewReader(valid), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, "record,type,date,spare,account,amount,count\n"+
+		"header,H,20200102,,,,\n"+
+		"detail,D,,,12345678,10.00,\n"+
+		"detail,D,,,87654321,20.00,\n"+
+		"footer,T,,,,,2\n", stdout.String())
+
+	csvPath := writeTemp(t, dir, "data.csv", stdout.String())
+	stdout.Reset()
+	code = run([]string{"from-csv", "--layout", layout, csvPath}, nil, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, valid, stdout.String())
+
+	header, footer := filepath.Join(dir, "header.json"), filepath.Join(dir, "footer.json")
+	body := filepath.Join(dir, "body.json")
+	code = run([]string{"to-json", "--layout", layout, "--header", header, "--footer", footer, "-o", body}, strings.NewReader(valid), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	data, err := ioutil.ReadFile(header)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type": "H", "date": "20200102", "spare": ""}`+"\n", string(data))
+	data, err = ioutil.ReadFile(body)
+	assert.NoError(t, err)
+	assert.Equal(t, "[\n"+
+		`  {"type": "D", "account": "12345678", "amount": "10.00"},`+"\n"+
+		`  {"type": "D", "account": "87654321", "amount": "20.00"}`+"\n"+
+		"]\n", string(data))
+
+	stdout.Reset()
+	code = run([]string{"from-json", "--layout", layout, "--header", header, "--footer", footer, body}, nil, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, valid, stdout.String())
+
+	stderr.Reset()
+	code = run([]string{"to-csv", "--layout", layout, "--header", header}, strings.NewReader(valid), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "gofmt256: --header and --footer must be given together\n", stderr.String())
+}
+
+func TestRunErrors(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 2, run(nil, nil, &stdout, &stderr))
+	assert.Equal(t, 2, run([]string{"frobnicate"}, nil, &stdout, &stderr))
+
+	stderr.Reset()
+	assert.Equal(t, 1, run([]string{"inspect"}, strings.NewReader(""), &stdout, &stderr))
+	assert.Equal(t, "gofmt256: --layout is required\n", stderr.String())
+}
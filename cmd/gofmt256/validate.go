@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/100x-fi/gofmt256"
+	"github.com/pkg/errors"
+)
+
+var errInvalid = errors.New("file is invalid")
+
+// Checks reported by validate.
+const (
+	checkLength   = "length"
+	checkOrder    = "order"
+	checkPadding  = "padding"
+	checkValue    = "value"
+	checkSequence = "sequence"
+	checkTotals   = "totals"
+	checkEncoding = "encoding"
+	checkOther    = "file"
+)
+
+type report struct {
+	File   string  `json:"file"`
+	Valid  bool    `json:"valid"`
+	Lines  int     `json:"lines"`
+	Errors []issue `json:"errors"`
+}
+
+type issue struct {
+	Check   string `json:"check"`
+	Line    int    `json:"line,omitempty"`
+	Offset  int64  `json:"offset"`
+	Section string `json:"section,omitempty"`
+	Record  string `json:"record,omitempty"`
+	Field   string `json:"field,omitempty"`
+	From    int    `json:"from,omitempty"`
+	To      int    `json:"to,omitempty"`
+	Value   string `json:"value,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+// validate checks every line of a file against a layout and prints a JSON
+// report. Sequence numbers and control totals are checked by parsing the
+// file once every line is valid, as totals of invalid records cannot be
+// trusted.
+func validate(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	var lf layoutFlags
+	lf.register(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	layout, err := lf.load()
+	if err != nil {
+		return err
+	}
+	data, err := readInput(flags, stdin)
+	if err != nil {
+		return err
+	}
+
+	r := report{File: "-", Errors: []issue{}}
+	if flags.NArg() == 1 {
+		r.File = flags.Arg(0)
+	}
+	lines, err := splitLines(layout, data)
+	if err != nil {
+		return err
+	}
+	r.Lines = len(lines)
+	r.Errors = checkLines(layout, lines)
+	if len(r.Errors) == 0 {
+		r.Errors = parseFile(layout, data, lines)
+	}
+	r.Valid = len(r.Errors) == 0
+
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		return err
+	}
+	if !r.Valid {
+		return errInvalid
+	}
+	return nil
+}
+
+func checkLines(layout *gofmt256.FileLayout, lines []line) []issue {
+	issues := []issue{}
+	if len(lines) < 2 {
+		return append(issues, issue{Check: checkOther, Reason: "file must contain at least header and footer"})
+	}
+
+	length, unit := recordLength(layout), widthUnit(layout)
+	for i, line := range lines {
+		at := issue{Line: i + 1, Offset: line.offset}
+		text := line.text
+		if line.err != nil {
+			is := at
+			is.Check, is.Reason = checkEncoding, line.err.Error()
+			issues = append(issues, is)
+		}
+
+		if line.width != length {
+			is := at
+			is.Check, is.Value = checkLength, text
+			is.Reason = fmt.Sprintf("line is %d %s long, expected %d", line.width, unit, length)
+			issues = append(issues, is)
+			continue
+		}
+
+		rt := line.recordType
+		section, l, err := layout.RecordLayout(at.Line, len(lines), rt)
+		at.Section = section
+		if err != nil {
+			is := at
+			is.Check, is.Value, is.Reason = checkOrder, rt, orderProblem(layout, rt, err.Error())
+			issues = append(issues, is)
+			continue
+		}
+		at.Record = l.Name
+		if l.RecordType != "" && rt != l.RecordType {
+			is := at
+			is.Check, is.Value = checkOrder, rt
+			is.Reason = fmt.Sprintf("%s must have record type `%s`", section, l.RecordType)
+			issues = append(issues, is)
+			continue
+		}
+
+		schema, err := gofmt256.Compile(l, layout.Options()...)
+		if err != nil {
+			is := at
+			is.Check, is.Reason = checkOther, err.Error()
+			return append(issues, is)
+		}
+		for _, fv := range schema.Inspect(text, layout.Options()...) {
+			if fv.Problem == "" {
+				continue
+			}
+			is := at
+			is.Check = checkValue
+			if fv.Err == gofmt256.ErrPadding {
+				is.Check = checkPadding
+			}
+			is.Field, is.From, is.To, is.Value, is.Reason = fv.Name, fv.From, fv.To, fv.Raw, fv.Problem
+			issues = append(issues, is)
+		}
+	}
+	return issues
+}
+
+// orderProblem explains a body line without a layout, which is often a
+// header or footer out of place.
+func orderProblem(layout *gofmt256.FileLayout, recordType, reason string) string {
+	switch {
+	case layout.Header.RecordType == recordType:
+		return "header record after the first line"
+	case layout.Footer.RecordType == recordType:
+		return "footer record before the last line"
+	}
+	return reason
+}
+
+// parseFile parses data, made of lines, to check sequence numbers and control
+// totals.
+func parseFile(layout *gofmt256.FileLayout, data []byte, lines []line) []issue {
+	header := gofmt256.LayoutRecord{Layout: layout.Header}
+	footer := gofmt256.LayoutRecord{Layout: layout.Footer}
+	var body []gofmt256.LayoutRecord
+	opts := append(layout.Options(), gofmt256.WithCollectAllErrors())
+	err := gofmt256.Parse(data, &header, &body, &footer, opts...)
+
+	issues := []issue{}
+	errs, ok := err.(gofmt256.MultiError)
+	if !ok && err != nil {
+		errs = gofmt256.MultiError{err}
+	}
+	for _, err := range errs {
+		var ve *gofmt256.ValidationError
+		if !errors.As(err, &ve) {
+			issues = append(issues, issue{Check: checkOther, Reason: err.Error()})
+			continue
+		}
+		is := issue{
+			Check:   checkValue,
+			Line:    ve.Line,
+			Offset:  ve.Offset,
+			Section: ve.Section,
+			Field:   ve.Field,
+			From:    ve.From,
+			To:      ve.To,
+			Value:   ve.Value,
+			Reason:  ve.Reason,
+		}
+		if ve.Line > 0 && ve.Line <= len(lines) {
+			_, l, err := layout.RecordLayout(ve.Line, len(lines), lines[ve.Line-1].recordType)
+			if err == nil {
+				is.Record = l.Name
+			}
+		}
+		switch {
+		case errors.Is(ve, gofmt256.ErrSequenceGap):
+			is.Check = checkSequence
+		case errors.Is(ve, gofmt256.ErrAggregateMismatch):
+			is.Check = checkTotals
+		case errors.Is(ve, gofmt256.ErrLineLength):
+			is.Check = checkLength
+		case errors.Is(ve, gofmt256.ErrPadding):
+			is.Check = checkPadding
+		case errors.Is(ve, gofmt256.ErrUnmappable):
+			is.Check = checkEncoding
+		}
+		issues = append(issues, is)
+	}
+	return issues
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+
+	"github.com/100x-fi/gofmt256"
+	"github.com/pkg/errors"
+)
+
+// goTypes maps the types of a spec to Go types. Any other type is used as a
+// Go type as it is, e.g. a type implementing gofmt256.Field256Marshaler. As
+// only time is imported, it must be declared in the generated package.
+var goTypes = map[string]string{
+	"":        "string",
+	"string":  "string",
+	"int":     "int",
+	"uint":    "uint",
+	"float":   "float64",
+	"bool":    "bool",
+	"decimal": "string",
+	"time":    "time.Time",
+}
+
+// generate returns the Go source of tagged structs for the records of s,
+// each checked with gofmt256.MustCompile when the package is loaded.
+func generate(s spec, pkg string, source string) ([]byte, error) {
+	if err := s.resolve(); err != nil {
+		return nil, err
+	}
+	length := s.Length
+	if length == 0 {
+		length = 256
+	}
+	for _, r := range s.Records {
+		if err := check(r, length); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by gofmt256gen from %s. DO NOT EDIT.\n\n", source)
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	buf.WriteString("import (\n")
+	if usesTime(s) {
+		buf.WriteString("\"time\"\n\n")
+	}
+	buf.WriteString("\"github.com/100x-fi/gofmt256\"\n)\n")
+
+	for _, r := range s.Records {
+		fmt.Fprintf(&buf, "\ntype %s struct {\n", r.Name)
+		for _, f := range r.Fields {
+			fmt.Fprintf(&buf, "%s %s `gofmt256:%s`\n", f.Name, goType(f.Type), strconv.Quote(strings.Join(subtags(f), ",")))
+		}
+		buf.WriteString("}\n")
+	}
+
+	buf.WriteString("\nvar (\n")
+	for _, r := range s.Records {
+		if length == 256 {
+			fmt.Fprintf(&buf, "_ = gofmt256.MustCompile(%s{})\n", r.Name)
+		} else {
+			fmt.Fprintf(&buf, "_ = gofmt256.MustCompile(%s{}, gofmt256.WithRecordLength(%d))\n", r.Name, length)
+		}
+	}
+	buf.WriteString(")\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to format generated code")
+	}
+	return src, nil
+}
+
+func goType(t string) string {
+	if goType, ok := goTypes[t]; ok {
+		return goType
+	}
+	return t
+}
+
+func usesTime(s spec) bool {
+	for _, r := range s.Records {
+		for _, f := range r.Fields {
+			if goType(f.Type) == "time.Time" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func subtags(f field) []string {
+	tags := []string{"from=" + strconv.Itoa(f.Start), "to=" + strconv.Itoa(f.End)}
+	if f.Align != "" && f.Align != "L" {
+		tags = append(tags, "align="+f.Align)
+	}
+	if f.Pad != "" && f.Pad != " " {
+		tags = append(tags, "padding='"+f.Pad+"'")
+	}
+	if f.Decimals != nil {
+		tags = append(tags, "decimals="+strconv.Itoa(*f.Decimals))
+	}
+	if f.Layout != "" {
+		tags = append(tags, "layout="+f.Layout)
+	}
+	if f.Extra != "" {
+		tags = append(tags, f.Extra)
+	}
+	return tags
+}
+
+// check validates the fields of r with the same rules as tagged structs, so
+// that a broken spec fails at generation rather than when the generated
+// package is loaded.
+func check(r record, length int) error {
+	l := &gofmt256.Layout{Name: r.Name}
+	for _, f := range r.Fields {
+		if t := goType(f.Type); t != "time.Time" && strings.Contains(t, ".") {
+			return errors.Errorf("%s.%s: type `%s` is from another package, declare it in the package of the generated file", r.Name, f.Name, f.Type)
+		}
+		lf := gofmt256.LayoutField{
+			Name:     f.Name,
+			From:     f.Start,
+			To:       f.End,
+			Align:    f.Align,
+			Padding:  f.Pad,
+			Decimals: f.Decimals,
+			Layout:   f.Layout,
+		}
+		switch f.Type {
+		case "decimal", "time", "string", "int", "uint", "float", "bool":
+			lf.Type = f.Type
+		}
+		if f.Type == "time" || goType(f.Type) == "time.Time" {
+			lf.Type = "time"
+		}
+		if err := setExtra(&lf, f.Extra); err != nil {
+			return errors.Wrapf(err, "%s.%s", r.Name, f.Name)
+		}
+		l.Fields = append(l.Fields, lf)
+	}
+	if _, err := gofmt256.Compile(l, gofmt256.WithRecordLength(length)); err != nil {
+		return errors.Wrap(err, r.Name)
+	}
+	return nil
+}
+
+func setExtra(lf *gofmt256.LayoutField, extra string) error {
+	if extra == "" {
+		return nil
+	}
+	for _, subtag := range strings.Split(extra, ",") {
+		if subtag == "seq" {
+			lf.Seq = true
+			continue
+		}
+		kv := strings.SplitN(subtag, "=", 2)
+		if len(kv) != 2 {
+			return errors.Errorf("malformed subtag `%s`", subtag)
+		}
+		switch kv[0] {
+		case "rounding":
+			lf.Rounding = kv[1]
+		case "tz":
+			lf.TZ = kv[1]
+		case "era":
+			lf.Era = kv[1]
+		case "zero":
+			lf.Zero = kv[1]
+		case "overflow":
+			lf.Overflow = kv[1]
+		case "agg":
+			lf.Agg = kv[1]
+		case "where":
+			lf.Where = kv[1]
+		default:
+			return errors.Errorf("unknown subtag `%s`", kv[0])
+		}
+	}
+	return nil
+}
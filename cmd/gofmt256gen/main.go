@@ -0,0 +1,131 @@
+// Command gofmt256gen generates Go structs with gofmt256 tags from a layout
+// spec, and a spec from existing structs.
+//
+// A spec is a CSV file with a header row naming the columns record, name,
+// start, end, length, type, align, pad, decimals, layout and extra, or a YAML
+// or JSON file of the same records. A field gives either its end or its
+// length, and a field without a start begins right after the previous one.
+// Extra holds any other subtags, e.g. `seq` or `agg=count`.
+//
+// Usage:
+//
+//	gofmt256gen -spec layout.csv [-package name] [-length 256] [-o records_gen.go]
+//	gofmt256gen -reverse [-type Header,Body] [-format yaml|csv] [-o layout.yaml] [dir]
+//
+// With go generate:
+//
+//	//go:generate go run github.com/100x-fi/gofmt256/cmd/gofmt256gen -spec layout.csv -o records_gen.go
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "gofmt256gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("gofmt256gen", flag.ContinueOnError)
+	specPath := flags.String("spec", "", "layout spec to generate structs from (.csv, .yaml or .json)")
+	pkg := flags.String("package", os.Getenv("GOPACKAGE"), "package of the generated code (default from the spec)")
+	length := flags.Int("length", 0, "record length, overriding the spec (default 256)")
+	output := flags.String("o", "", "output file (default stdout)")
+	rev := flags.Bool("reverse", false, "generate a spec from the tagged structs of a package")
+	typeNames := flags.String("type", "", "comma-separated structs to include with -reverse (default all)")
+	outputFormat := flags.String("format", "", "spec format with -reverse, yaml or csv (default from -o, else yaml)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var out []byte
+	var err error
+	if *rev {
+		dir := "."
+		if flags.NArg() > 0 {
+			dir = flags.Arg(0)
+		}
+		format := *outputFormat
+		if format == "" && strings.EqualFold(filepath.Ext(*output), ".csv") {
+			format = "csv"
+		}
+		out, err = reverseSpec(dir, *typeNames, format)
+	} else {
+		out, err = generateFile(*specPath, *pkg, *length)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		_, err = stdout.Write(out)
+		return err
+	}
+	return ioutil.WriteFile(*output, out, 0644)
+}
+
+func generateFile(path, pkg string, length int) ([]byte, error) {
+	if path == "" {
+		return nil, errors.New("-spec is required")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s, err := readSpec(f, path)
+	if err != nil {
+		return nil, err
+	}
+	if length != 0 {
+		s.Length = length
+	}
+	if pkg == "" {
+		pkg = s.Package
+	}
+	if pkg == "" {
+		return nil, errors.New("-package is required outside of go generate")
+	}
+	return generate(s, pkg, filepath.Base(path))
+}
+
+func reverseSpec(dir, typeNames, format string) ([]byte, error) {
+	var only []string
+	if typeNames != "" {
+		only = strings.Split(typeNames, ",")
+	}
+	s, err := reverse(dir, only)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case "", "yaml":
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(s); err != nil {
+			return nil, err
+		}
+	case "csv":
+		if err := writeCSVSpec(&buf, s); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.Errorf("format must be yaml or csv, got `%s`", format)
+	}
+	return buf.Bytes(), nil
+}
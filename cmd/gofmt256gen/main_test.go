@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const csvSpec = `record,name,start,length,type,align,pad,decimals,extra
+header,record type,1,1,,,,,
+header,sequence no,,6,int,R,0,,seq
+header,spare,,13,,,,,
+detail,record type,1,1,,,,,
+detail,amount,,10,decimal,R,0,2,
+detail,spare,,9,,,,,
+`
+
+func TestGenerate(t *testing.T) {
+	s, err := readSpec(strings.NewReader(csvSpec), "layout.csv")
+	assert.NoError(t, err)
+	s.Length = 20
+
+	src, err := generate(s, "bank", "layout.csv")
+	assert.NoError(t, err)
+	want := "// Code generated by gofmt256gen from layout.csv. DO NOT EDIT.\n" +
+		"\n" +
+		"package bank\n" +
+		"\n" +
+		"import (\n" +
+		"\t\"github.com/100x-fi/gofmt256\"\n" +
+		")\n" +
+		"\n" +
+		"type Header struct {\n" +
+		"\tRecordType string `gofmt256:\"from=1,to=1\"`\n" +
+		"\tSequenceNo int    `gofmt256:\"from=2,to=7,align=R,padding='0',seq\"`\n" +
+		"\tSpare      string `gofmt256:\"from=8,to=20\"`\n" +
+		"}\n" +
+		"\n" +
+		"type Detail struct {\n" +
+		"\tRecordType string `gofmt256:\"from=1,to=1\"`\n" +
+		"\tAmount     string `gofmt256:\"from=2,to=11,align=R,padding='0',decimals=2\"`\n" +
+		"\tSpare      string `gofmt256:\"from=12,to=20\"`\n" +
+		"}\n" +
+		"\n" +
+		"var (\n" +
+		"\t_ = gofmt256.MustCompile(Header{}, gofmt256.WithRecordLength(20))\n" +
+		"\t_ = gofmt256.MustCompile(Detail{}, gofmt256.WithRecordLength(20))\n" +
+		")\n"
+	assert.Equal(t, want, string(src))
+}
+
+func TestGenerateInvalidSpec(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+	}{
+		{name: "missing length", spec: "record,name,start\nheader,type,1\n"},
+		{name: "end does not match length", spec: "record,name,start,end,length\nheader,type,1,2,3\n"},
+		{name: "overlapping fields", spec: "record,name,start,end\nheader,a,1,10\nheader,b,10,256\n"},
+		{name: "gap", spec: "record,name,start,end\nheader,a,1,10\n"},
+		{name: "unknown subtag", spec: "record,name,start,end,extra\nheader,a,1,256,foo=bar\n"},
+		{name: "type from another package", spec: "record,name,start,end,type\nheader,a,1,256,*big.Rat\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := readSpec(strings.NewReader(tt.spec), "layout.csv")
+			assert.NoError(t, err)
+			_, err = generate(s, "bank", "layout.csv")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestIdentifier(t *testing.T) {
+	for name, want := range map[string]string{
+		"record type": "RecordType",
+		"ref_1":       "Ref1",
+		"merchant id": "MerchantID",
+		"id":          "ID",
+		"api-url":     "APIURL",
+		"1st name":    "F1stName",
+		"NAME":        "NAME",
+	} {
+		assert.Equal(t, want, identifier(name), name)
+	}
+}
+
+func TestReverse(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gofmt256gen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := readSpec(strings.NewReader(csvSpec), "layout.csv")
+	assert.NoError(t, err)
+	s.Length = 20
+	src, err := generate(s, "bank", "layout.csv")
+	assert.NoError(t, err)
+	err = ioutil.WriteFile(filepath.Join(dir, "records_gen.go"), src, 0644)
+	assert.NoError(t, err)
+
+	var out bytes.Buffer
+	err = run([]string{"-reverse", "-type", "Detail", "-format", "csv", dir}, &out)
+	assert.NoError(t, err)
+	assert.Equal(t, "record,name,start,end,length,type,align,pad,decimals,layout,extra\n"+
+		"Detail,RecordType,1,1,,,,,,,\n"+
+		"Detail,Amount,2,11,,decimal,R,0,2,,\n"+
+		"Detail,Spare,12,20,,,,,,,\n", out.String())
+
+	reversed, err := reverse(dir, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 20, reversed.Length)
+	again, err := generate(reversed, "bank", "layout.csv")
+	assert.NoError(t, err)
+	assert.Equal(t, string(src), string(again))
+}
+
+func TestReverseLen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gofmt256gen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := "package bank\n\n" +
+		"type Detail struct {\n" +
+		"\tRecordType string `gofmt256:\"len=1\"`\n" +
+		"\tAccount    string `gofmt256:\"len=10\"`\n" +
+		"\tSpare      string `gofmt256:\"from=12,width=9\"`\n" +
+		"}\n"
+	err = ioutil.WriteFile(filepath.Join(dir, "records.go"), []byte(src), 0644)
+	assert.NoError(t, err)
+
+	var out bytes.Buffer
+	err = run([]string{"-reverse", "-format", "csv", dir}, &out)
+	assert.NoError(t, err)
+	assert.Equal(t, "record,name,start,end,length,type,align,pad,decimals,layout,extra\n"+
+		"Detail,RecordType,1,1,,,,,,,\n"+
+		"Detail,Account,2,11,,,,,,,\n"+
+		"Detail,Spare,12,20,,,,,,,\n", out.String())
+}
+
+func TestReverseUnexported(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gofmt256gen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := "package bank\n\n" +
+		"type Detail struct {\n" +
+		"\tRecordType string `gofmt256:\"from=1,to=1\"`\n" +
+		"\tnote       string `gofmt256:\"from=2,to=5\"`\n" +
+		"\tSpare      string `gofmt256:\"from=2,to=20\"`\n" +
+		"}\n"
+	err = ioutil.WriteFile(filepath.Join(dir, "records.go"), []byte(src), 0644)
+	assert.NoError(t, err)
+
+	var out bytes.Buffer
+	err = run([]string{"-reverse", "-format", "csv", dir}, &out)
+	assert.NoError(t, err)
+	assert.Equal(t, "record,name,start,end,length,type,align,pad,decimals,layout,extra\n"+
+		"Detail,RecordType,1,1,,,,,,,\n"+
+		"Detail,Spare,2,20,,,,,,,\n", out.String())
+}
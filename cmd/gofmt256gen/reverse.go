@@ -0,0 +1,122 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/100x-fi/gofmt256/internal/golayout"
+	"github.com/pkg/errors"
+)
+
+// reverse reads the structs with gofmt256 tags of the package in dir and
+// returns them as a spec. Only the structs named in only are included, unless
+// only is empty.
+func reverse(dir string, only []string) (spec, error) {
+	pkg, structs, err := golayout.Parse(dir)
+	if err != nil {
+		return spec{}, err
+	}
+
+	wanted := make(map[string]bool, len(only))
+	for _, name := range only {
+		wanted[name] = true
+	}
+
+	s := spec{Package: pkg}
+	for _, st := range structs {
+		if len(wanted) > 0 && !wanted[st.Name] {
+			continue
+		}
+		r := record{Name: st.Name}
+		next := 1
+		for _, f := range st.Fields {
+			rf, err := reverseField(f.Name, f.Type, f.Tag, next)
+			if err != nil {
+				return spec{}, errors.Wrapf(err, "%s.%s", st.Name, f.Name)
+			}
+			r.Fields = append(r.Fields, rf)
+			next = rf.End + 1
+		}
+		s.Records = append(s.Records, r)
+	}
+	if len(s.Records) == 0 {
+		return spec{}, errors.New("no struct with gofmt256 tags is found")
+	}
+
+	// the record length is not in the tags, but the last field ends there
+	for _, r := range s.Records {
+		for _, f := range r.Fields {
+			if f.End > s.Length {
+				s.Length = f.End
+			}
+		}
+	}
+	if s.Length == 256 {
+		s.Length = 0
+	}
+	return s, nil
+}
+
+// reverseField reads a field from its tag. A field tagged with len but not
+// from starts at next, the position after the previous field.
+func reverseField(name, goType, tag string, next int) (field, error) {
+	f := field{Name: name}
+	var length int
+	var extra []string
+	for _, subtag := range strings.Split(tag, ",") {
+		kv := strings.SplitN(subtag, "=", 2)
+		if len(kv) != 2 {
+			extra = append(extra, subtag)
+			continue
+		}
+		var err error
+		switch kv[0] {
+		case "from":
+			f.Start, err = strconv.Atoi(kv[1])
+		case "to":
+			f.End, err = strconv.Atoi(kv[1])
+		case "len", "width":
+			length, err = strconv.Atoi(kv[1])
+		case "align":
+			f.Align = kv[1]
+		case "padding":
+			f.Pad = strings.ReplaceAll(kv[1], "'", "")
+		case "decimals":
+			var decimals int
+			decimals, err = strconv.Atoi(kv[1])
+			f.Decimals = &decimals
+		case "layout":
+			f.Layout = kv[1]
+		default:
+			extra = append(extra, subtag)
+		}
+		if err != nil {
+			return field{}, errors.Errorf("`%s` must be a number", kv[0])
+		}
+	}
+	f.Extra = strings.Join(extra, ",")
+	if length > 0 {
+		switch {
+		case f.Start == 0 && f.End == 0:
+			f.Start, f.End = next, next+length-1
+		case f.Start == 0:
+			f.Start = f.End - length + 1
+		case f.End == 0:
+			f.End = f.Start + length - 1
+		}
+	}
+
+	switch goType {
+	case "string":
+		if f.Decimals != nil {
+			f.Type = "decimal"
+		}
+	case "float64":
+		f.Type = "float"
+	case "time.Time":
+		f.Type = "time"
+	default:
+		f.Type = goType
+	}
+	return f, nil
+}
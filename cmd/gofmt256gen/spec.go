@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// spec describes the records of a file as transcribed from a bank
+// specification. A field gives either its end or its length; a field without
+// a start begins right after the previous one.
+type spec struct {
+	Package string   `yaml:"package,omitempty"`
+	Length  int      `yaml:"length,omitempty"`
+	Records []record `yaml:"records"`
+}
+
+type record struct {
+	Name   string  `yaml:"name"`
+	Fields []field `yaml:"fields"`
+}
+
+type field struct {
+	Name     string `yaml:"name"`
+	Start    int    `yaml:"start,omitempty"`
+	End      int    `yaml:"end,omitempty"`
+	Length   int    `yaml:"length,omitempty"`
+	Type     string `yaml:"type,omitempty"`
+	Align    string `yaml:"align,omitempty"`
+	Pad      string `yaml:"pad,omitempty"`
+	Decimals *int   `yaml:"decimals,omitempty"`
+	Layout   string `yaml:"layout,omitempty"`
+	Extra    string `yaml:"extra,omitempty"`
+}
+
+var csvColumns = []string{"record", "name", "start", "end", "length", "type", "align", "pad", "decimals", "layout", "extra"}
+
+// readSpec reads a CSV spec, with one row per field and a header row naming
+// the columns, or a YAML or JSON spec, depending on the extension of path.
+func readSpec(r io.Reader, path string) (spec, error) {
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return readCSVSpec(r)
+	}
+
+	var s spec
+	if err := yaml.NewDecoder(r).Decode(&s); err != nil {
+		return spec{}, errors.Wrap(err, "unable to read spec")
+	}
+	return s, nil
+}
+
+func readCSVSpec(r io.Reader) (spec, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return spec{}, errors.Wrap(err, "unable to read spec")
+	}
+	if len(rows) == 0 {
+		return spec{}, errors.New("spec is empty")
+	}
+
+	columns := make(map[string]int)
+	for i, name := range rows[0] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "alignment":
+			name = "align"
+		case "padding":
+			name = "pad"
+		}
+		columns[name] = i
+	}
+	for _, name := range []string{"record", "name"} {
+		if _, ok := columns[name]; !ok {
+			return spec{}, errors.Errorf("column `%s` is missing", name)
+		}
+	}
+
+	var s spec
+	for n, row := range rows[1:] {
+		value := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+		number := func(column string) (int, error) {
+			if value(column) == "" {
+				return 0, nil
+			}
+			i, err := strconv.Atoi(value(column))
+			if err != nil {
+				return 0, errors.Errorf("row %d: `%s` must be a number", n+2, column)
+			}
+			return i, nil
+		}
+
+		f := field{
+			Name:   value("name"),
+			Type:   value("type"),
+			Align:  value("align"),
+			Layout: value("layout"),
+			Extra:  value("extra"),
+		}
+		// padding is not trimmed so that it can be a space
+		if i, ok := columns["pad"]; ok && i < len(row) {
+			f.Pad = row[i]
+		}
+		if f.Start, err = number("start"); err != nil {
+			return spec{}, err
+		}
+		if f.End, err = number("end"); err != nil {
+			return spec{}, err
+		}
+		if f.Length, err = number("length"); err != nil {
+			return spec{}, err
+		}
+		if value("decimals") != "" {
+			decimals, err := number("decimals")
+			if err != nil {
+				return spec{}, err
+			}
+			f.Decimals = &decimals
+		}
+
+		name := value("record")
+		if len(s.Records) == 0 || s.Records[len(s.Records)-1].Name != name {
+			s.Records = append(s.Records, record{Name: name})
+		}
+		s.Records[len(s.Records)-1].Fields = append(s.Records[len(s.Records)-1].Fields, f)
+	}
+	return s, nil
+}
+
+func writeCSVSpec(w io.Writer, s spec) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvColumns); err != nil {
+		return err
+	}
+	for _, r := range s.Records {
+		for _, f := range r.Fields {
+			row := []string{r.Name, f.Name, itoa(f.Start), itoa(f.End), itoa(f.Length), f.Type, f.Align, f.Pad, "", f.Layout, f.Extra}
+			if f.Decimals != nil {
+				row[8] = strconv.Itoa(*f.Decimals)
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func itoa(i int) string {
+	if i == 0 {
+		return ""
+	}
+	return strconv.Itoa(i)
+}
+
+// resolve names the records and fields as exported Go identifiers and fills
+// in the start and end of every field.
+func (s *spec) resolve() error {
+	for i := range s.Records {
+		r := &s.Records[i]
+		r.Name = identifier(r.Name)
+		if r.Name == "" {
+			return errors.Errorf("name of record %d is missing", i+1)
+		}
+
+		next := 1
+		for j := range r.Fields {
+			f := &r.Fields[j]
+			f.Name = identifier(f.Name)
+			if f.Name == "" {
+				return errors.Errorf("%s: name of field %d is missing", r.Name, j+1)
+			}
+			if f.Start == 0 {
+				f.Start = next
+			}
+			switch {
+			case f.End == 0 && f.Length == 0:
+				return errors.Errorf("%s.%s: end or length is missing", r.Name, f.Name)
+			case f.End == 0:
+				f.End = f.Start + f.Length - 1
+			case f.Length != 0 && f.End != f.Start+f.Length-1:
+				return errors.Errorf("%s.%s: end %d does not match start %d and length %d", r.Name, f.Name, f.End, f.Start, f.Length)
+			}
+			f.Length = 0
+			next = f.End + 1
+		}
+	}
+	return nil
+}
+
+// initialisms are the words that golint expects in upper case.
+var initialisms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "CPU": true, "CSS": true, "DNS": true,
+	"EOF": true, "GUID": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true,
+	"IP": true, "JSON": true, "LHS": true, "QPS": true, "RAM": true, "RHS": true,
+	"RPC": true, "SLA": true, "SMTP": true, "SQL": true, "SSH": true, "TCP": true,
+	"TLS": true, "TTL": true, "UDP": true, "UI": true, "UID": true, "UUID": true,
+	"URI": true, "URL": true, "UTF8": true, "VM": true, "XML": true, "XMPP": true,
+	"XSRF": true, "XSS": true,
+}
+
+// identifier turns a name such as `record type`, `ref_1` or `merchant id`
+// into an exported Go identifier such as `RecordType`, `Ref1` or `MerchantID`.
+func identifier(name string) string {
+	var b strings.Builder
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		if b.Len() == 0 && unicode.IsDigit([]rune(word)[0]) {
+			b.WriteString("F")
+		}
+		if upper := strings.ToUpper(word); initialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		r := []rune(word)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+	return b.String()
+}
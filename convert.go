@@ -0,0 +1,463 @@
+package gofmt256
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RecordColumn is the CSV column, and the key of JSON body records, naming
+// the layout of a record: header, footer or the name of a body layout. It is
+// written when records of more than one layout share an output.
+const RecordColumn = "record"
+
+// LayoutFile is the content of a file described by a FileLayout.
+type LayoutFile struct {
+	Header LayoutRecord
+	Body   []LayoutRecord
+	Footer LayoutRecord
+}
+
+// Parse reads a file of l. Options are added to those of l.
+func (l *FileLayout) Parse(data []byte, opts ...Option) (*LayoutFile, error) {
+	f := &LayoutFile{
+		Header: LayoutRecord{Layout: l.Header},
+		Footer: LayoutRecord{Layout: l.Footer},
+	}
+	if err := Parse(data, &f.Header, &f.Body, &f.Footer, append(l.Options(), opts...)...); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Build returns the file of f. Options are added to those of l.
+func (l *FileLayout) Build(f *LayoutFile, opts ...Option) (string, error) {
+	body := f.Body
+	if body == nil {
+		body = []LayoutRecord{}
+	}
+	return New(f.Header, body, f.Footer, append(l.Options(), opts...)...).Build()
+}
+
+// WriteCSV writes the records of section of f as CSV, with a header row of
+// field names, or every record with a RecordColumn when section is empty.
+// Times are written in RFC 3339.
+func (l *FileLayout) WriteCSV(w io.Writer, f *LayoutFile, section string) error {
+	records, err := l.sectionRecords(f, section)
+	if err != nil {
+		return err
+	}
+	columns, err := l.columns(section)
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	for _, r := range records {
+		row := make([]string, len(columns))
+		for i, column := range columns {
+			if column == RecordColumn && l.hasRecordColumn(section) {
+				row[i] = l.recordName(r.section, r.Layout)
+				continue
+			}
+			row[i] = formatValue(r.Values[column])
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReadCSV reads records written by WriteCSV into section of f, or into every
+// section when section is empty. Empty cells are left to the zero value of
+// their field.
+func (l *FileLayout) ReadCSV(r io.Reader, f *LayoutFile, section string) error {
+	if err := checkSection(section); err != nil {
+		return err
+	}
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return errors.Wrap(err, "unable to read CSV")
+	}
+	if len(rows) == 0 {
+		return errors.New("CSV must have a header row")
+	}
+
+	columns := rows[0]
+	records := make([]sectionRecord, 0, len(rows)-1)
+	for i, row := range rows[1:] {
+		values := make(map[string]interface{}, len(columns))
+		for j, column := range columns {
+			if j < len(row) && row[j] != "" {
+				values[column] = row[j]
+			}
+		}
+		record, err := l.record(section, values)
+		if err != nil {
+			return errors.Wrapf(err, "row %d", i+2)
+		}
+		records = append(records, record)
+	}
+	return l.setRecords(f, section, records)
+}
+
+// WriteJSON writes section of f as JSON: an object for the header or the
+// footer, an array for the body, or an object with header, body and footer
+// when section is empty. Fields are written in layout order.
+func (l *FileLayout) WriteJSON(w io.Writer, f *LayoutFile, section string) error {
+	if _, err := l.sectionRecords(f, section); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	var err error
+	switch section {
+	case SectionHeader:
+		err = l.writeJSONRecord(&buf, f.Header, SectionHeader)
+	case SectionFooter:
+		err = l.writeJSONRecord(&buf, f.Footer, SectionFooter)
+	case SectionBody:
+		err = l.writeJSONBody(&buf, f.Body, "")
+	default:
+		buf.WriteString("{\n  \"header\": ")
+		if err = l.writeJSONRecord(&buf, f.Header, SectionHeader); err != nil {
+			return err
+		}
+		buf.WriteString(",\n  \"body\": ")
+		if err = l.writeJSONBody(&buf, f.Body, "  "); err != nil {
+			return err
+		}
+		buf.WriteString(",\n  \"footer\": ")
+		err = l.writeJSONRecord(&buf, f.Footer, SectionFooter)
+		buf.WriteString("\n}")
+	}
+	if err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// ReadJSON reads JSON written by WriteJSON into section of f, or into every
+// section when section is empty. Null values are left to the zero value of
+// their field.
+func (l *FileLayout) ReadJSON(r io.Reader, f *LayoutFile, section string) error {
+	if err := checkSection(section); err != nil {
+		return err
+	}
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	var records []sectionRecord
+	add := func(section string, values map[string]interface{}) error {
+		record, err := l.record(section, values)
+		if err != nil {
+			return err
+		}
+		records = append(records, record)
+		return nil
+	}
+
+	switch section {
+	case SectionHeader, SectionFooter:
+		var values map[string]interface{}
+		if err := dec.Decode(&values); err != nil {
+			return errors.Wrap(err, "unable to read JSON")
+		}
+		if err := add(section, values); err != nil {
+			return err
+		}
+	case SectionBody:
+		var body []map[string]interface{}
+		if err := dec.Decode(&body); err != nil {
+			return errors.Wrap(err, "unable to read JSON")
+		}
+		for i, values := range body {
+			if err := add(SectionBody, values); err != nil {
+				return errors.Wrapf(err, "body[%d]", i)
+			}
+		}
+	default:
+		var file struct {
+			Header map[string]interface{}   `json:"header"`
+			Body   []map[string]interface{} `json:"body"`
+			Footer map[string]interface{}   `json:"footer"`
+		}
+		if err := dec.Decode(&file); err != nil {
+			return errors.Wrap(err, "unable to read JSON")
+		}
+		if file.Header == nil || file.Footer == nil {
+			return errors.New("JSON must have header and footer")
+		}
+		if err := add(SectionHeader, file.Header); err != nil {
+			return errors.Wrap(err, SectionHeader)
+		}
+		for i, values := range file.Body {
+			if err := add(SectionBody, values); err != nil {
+				return errors.Wrapf(err, "body[%d]", i)
+			}
+		}
+		if err := add(SectionFooter, file.Footer); err != nil {
+			return errors.Wrap(err, SectionFooter)
+		}
+	}
+	return l.setRecords(f, section, records)
+}
+
+// sectionRecord is a LayoutRecord along with its section.
+type sectionRecord struct {
+	LayoutRecord
+	section string
+}
+
+func (l *FileLayout) sectionRecords(f *LayoutFile, section string) ([]sectionRecord, error) {
+	if err := checkSection(section); err != nil {
+		return nil, err
+	}
+	var records []sectionRecord
+	if section == "" || section == SectionHeader {
+		records = append(records, sectionRecord{f.Header, SectionHeader})
+	}
+	if section == "" || section == SectionBody {
+		for _, r := range f.Body {
+			records = append(records, sectionRecord{r, SectionBody})
+		}
+	}
+	if section == "" || section == SectionFooter {
+		records = append(records, sectionRecord{f.Footer, SectionFooter})
+	}
+	for _, r := range records {
+		if r.Layout == nil {
+			return nil, errors.Errorf("%s record has no layout", r.section)
+		}
+	}
+	return records, nil
+}
+
+func checkSection(section string) error {
+	switch section {
+	case "", SectionHeader, SectionBody, SectionFooter:
+		return nil
+	}
+	return errors.Errorf("section must be header, body, footer or empty, got `%s`", section)
+}
+
+// hasRecordColumn reports whether records of section are written with a
+// RecordColumn.
+func (l *FileLayout) hasRecordColumn(section string) bool {
+	return section == "" || (section == SectionBody && len(l.Body) > 1)
+}
+
+// columns returns the field names of the layouts of section, in order, after
+// a RecordColumn when there is one.
+func (l *FileLayout) columns(section string) ([]string, error) {
+	var layouts []*Layout
+	if section == "" || section == SectionHeader {
+		layouts = append(layouts, l.Header)
+	}
+	if section == "" || section == SectionBody {
+		layouts = append(layouts, l.Body...)
+	}
+	if section == "" || section == SectionFooter {
+		layouts = append(layouts, l.Footer)
+	}
+
+	var columns []string
+	seen := make(map[string]bool)
+	if l.hasRecordColumn(section) {
+		columns = append(columns, RecordColumn)
+		seen[RecordColumn] = true
+	}
+	for _, layout := range layouts {
+		for _, f := range layout.Fields {
+			if f.Name == RecordColumn && l.hasRecordColumn(section) {
+				return nil, errors.Errorf("[%s] field has the name of the record column", f.Name)
+			}
+			if !seen[f.Name] {
+				seen[f.Name] = true
+				columns = append(columns, f.Name)
+			}
+		}
+	}
+	return columns, nil
+}
+
+// recordName returns the value of the RecordColumn for a record of layout in
+// section.
+func (l *FileLayout) recordName(section string, layout *Layout) string {
+	if section != SectionBody {
+		return section
+	}
+	if layout.Name != "" {
+		return layout.Name
+	}
+	return SectionBody
+}
+
+// record returns the record of values, read into section or, when section
+// is empty, into the section named by their RecordColumn.
+func (l *FileLayout) record(section string, values map[string]interface{}) (sectionRecord, error) {
+	var name string
+	if l.hasRecordColumn(section) {
+		name, _ = values[RecordColumn].(string)
+		delete(values, RecordColumn)
+	}
+	if section == "" {
+		switch name {
+		case SectionHeader, SectionFooter:
+			section = name
+		case "":
+			return sectionRecord{}, errors.Errorf("%s is missing", RecordColumn)
+		default:
+			section = SectionBody
+		}
+	}
+
+	var layout *Layout
+	switch section {
+	case SectionHeader:
+		layout = l.Header
+	case SectionFooter:
+		layout = l.Footer
+	case SectionBody:
+		if len(l.Body) == 1 {
+			layout = l.Body[0]
+			break
+		}
+		for _, body := range l.Body {
+			if l.recordName(SectionBody, body) == name {
+				layout = body
+				break
+			}
+		}
+		if layout == nil {
+			return sectionRecord{}, errors.Errorf("no body layout is named `%s`", name)
+		}
+	}
+
+	fields := make(map[string]bool, len(layout.Fields))
+	for _, f := range layout.Fields {
+		fields[f.Name] = true
+	}
+	for name, v := range values {
+		if v == nil {
+			delete(values, name)
+			continue
+		}
+		if !fields[name] {
+			return sectionRecord{}, errors.Errorf("[%s] field is not in layout `%s`", name, l.recordName(section, layout))
+		}
+	}
+	return sectionRecord{LayoutRecord{Layout: layout, Values: values}, section}, nil
+}
+
+// setRecords sets records into section of f, or into every section when
+// section is empty. The header and the footer take exactly one record.
+func (l *FileLayout) setRecords(f *LayoutFile, section string, records []sectionRecord) error {
+	var headers, footers int
+	var body []LayoutRecord
+	for _, r := range records {
+		switch r.section {
+		case SectionHeader:
+			f.Header = r.LayoutRecord
+			headers++
+		case SectionFooter:
+			f.Footer = r.LayoutRecord
+			footers++
+		default:
+			body = append(body, r.LayoutRecord)
+		}
+	}
+	if (section == "" || section == SectionHeader) && headers != 1 {
+		return errors.Errorf("expected 1 header record, got %d", headers)
+	}
+	if (section == "" || section == SectionFooter) && footers != 1 {
+		return errors.Errorf("expected 1 footer record, got %d", footers)
+	}
+	if section == "" || section == SectionBody {
+		f.Body = body
+	}
+	return nil
+}
+
+func (l *FileLayout) writeJSONBody(buf *bytes.Buffer, body []LayoutRecord, indent string) error {
+	if len(body) == 0 {
+		buf.WriteString("[]")
+		return nil
+	}
+	buf.WriteString("[")
+	for i, r := range body {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n  " + indent)
+		if err := l.writeJSONRecord(buf, r, SectionBody); err != nil {
+			return errors.Wrapf(err, "body[%d]", i)
+		}
+	}
+	buf.WriteString("\n" + indent + "]")
+	return nil
+}
+
+// writeJSONRecord writes r as a JSON object with its fields in layout order.
+func (l *FileLayout) writeJSONRecord(buf *bytes.Buffer, r LayoutRecord, section string) error {
+	if r.Layout == nil {
+		return errors.Errorf("%s record has no layout", section)
+	}
+	buf.WriteString("{")
+	sep := ""
+	write := func(name string, v interface{}) error {
+		key, _ := json.Marshal(name)
+		value, err := json.Marshal(v)
+		if err != nil {
+			return errors.Wrapf(err, "[%s] unable to write value", name)
+		}
+		buf.WriteString(sep)
+		buf.Write(key)
+		buf.WriteString(": ")
+		buf.Write(value)
+		sep = ", "
+		return nil
+	}
+	if section == SectionBody && l.hasRecordColumn(section) {
+		if err := write(RecordColumn, l.recordName(section, r.Layout)); err != nil {
+			return err
+		}
+	}
+	for _, f := range r.Layout.Fields {
+		v, ok := r.Values[f.Name]
+		if !ok {
+			continue
+		}
+		if t, ok := v.(time.Time); ok {
+			v = t.Format(time.RFC3339)
+		}
+		if err := write(f.Name, v); err != nil {
+			return err
+		}
+	}
+	buf.WriteString("}")
+	return nil
+}
+
+// formatValue returns the CSV cell of a value of a LayoutRecord.
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.Format(time.RFC3339)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,150 @@
+package gofmt256_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/100x-fi/gofmt256"
+	"github.com/stretchr/testify/assert"
+)
+
+const settlementLayout = `
+length: 20
+header:
+  name: header
+  record_type: H
+  fields:
+    - {name: type, from: 1, to: 1}
+    - {name: date, from: 2, to: 9, type: time, layout: "20060102"}
+    - {name: spare, from: 10, to: 20}
+body:
+  - name: payment
+    record_type: D
+    fields:
+      - {name: type, from: 1, to: 1}
+      - {name: account, from: 2, to: 11}
+      - {name: amount, from: 12, to: 20, align: R, padding: "0", type: decimal, decimals: 2}
+  - name: note
+    record_type: N
+    fields:
+      - {name: type, from: 1, to: 1}
+      - {name: text, from: 2, to: 20}
+footer:
+  name: trailer
+  record_type: T
+  fields:
+    - {name: type, from: 1, to: 1}
+    - {name: count, from: 2, to: 7, align: R, padding: "0", type: int, agg: count}
+    - {name: spare, from: 8, to: 20}
+`
+
+const settlementFile = "H20200903           \n" +
+	"D1234567890000051550\n" +
+	"Nsee \"ref\", page 2  \n" +
+	"D987       000002000\n" +
+	"T000003             \n"
+
+func TestConvertCSV(t *testing.T) {
+	l, err := gofmt256.ParseFileLayout([]byte(settlementLayout))
+	assert.NoError(t, err)
+	f, err := l.Parse([]byte(settlementFile))
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = l.WriteCSV(&buf, f, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "record,type,date,spare,account,amount,text,count\n"+
+		"header,H,2020-09-03T00:00:00Z,,,,,\n"+
+		"payment,D,,,1234567890,515.50,,\n"+
+		"note,N,,,,,\"see \"\"ref\"\", page 2\",\n"+
+		"payment,D,,,987,20.00,,\n"+
+		"footer,T,,,,,,3\n", buf.String())
+
+	var read gofmt256.LayoutFile
+	err = l.ReadCSV(&buf, &read, "")
+	assert.NoError(t, err)
+	got, err := l.Build(&read)
+	assert.NoError(t, err)
+	assert.Equal(t, settlementFile, got)
+
+	buf.Reset()
+	err = l.WriteCSV(&buf, f, gofmt256.SectionFooter)
+	assert.NoError(t, err)
+	assert.Equal(t, "type,count,spare\nT,3,\n", buf.String())
+	buf.Reset()
+	err = l.WriteCSV(&buf, f, gofmt256.SectionBody)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(buf.String(), "record,type,account,amount,text\npayment,D,1234567890,515.50,\n"))
+
+	// the footer is computed again when the file is built
+	read = gofmt256.LayoutFile{}
+	assert.NoError(t, l.ReadCSV(strings.NewReader("type,date\nH,2020-09-03T00:00:00Z\n"), &read, gofmt256.SectionHeader))
+	assert.NoError(t, l.ReadCSV(strings.NewReader("type\nT\n"), &read, gofmt256.SectionFooter))
+	assert.NoError(t, l.ReadCSV(&buf, &read, gofmt256.SectionBody))
+	got, err = l.Build(&read)
+	assert.NoError(t, err)
+	assert.Equal(t, settlementFile, got)
+}
+
+func TestConvertJSON(t *testing.T) {
+	l, err := gofmt256.ParseFileLayout([]byte(settlementLayout))
+	assert.NoError(t, err)
+	f, err := l.Parse([]byte(settlementFile))
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = l.WriteJSON(&buf, f, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{
+  "header": {"type": "H", "date": "2020-09-03T00:00:00Z", "spare": ""},
+  "body": [
+    {"record": "payment", "type": "D", "account": "1234567890", "amount": "515.50"},
+    {"record": "note", "type": "N", "text": "see \"ref\", page 2"},
+    {"record": "payment", "type": "D", "account": "987", "amount": "20.00"}
+  ],
+  "footer": {"type": "T", "count": 3, "spare": ""}
+}
+`, buf.String())
+
+	var read gofmt256.LayoutFile
+	err = l.ReadJSON(&buf, &read, "")
+	assert.NoError(t, err)
+	got, err := l.Build(&read)
+	assert.NoError(t, err)
+	assert.Equal(t, settlementFile, got)
+
+	buf.Reset()
+	err = l.WriteJSON(&buf, f, gofmt256.SectionHeader)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type": "H", "date": "2020-09-03T00:00:00Z", "spare": ""}`+"\n", buf.String())
+}
+
+func TestConvertErrors(t *testing.T) {
+	l, err := gofmt256.ParseFileLayout([]byte(settlementLayout))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		csv     string
+		section string
+	}{
+		{name: "missing record column", csv: "type\nH\n"},
+		{name: "unknown body layout", csv: "record,type\nheader,H\nrefund,R\nfooter,T\n"},
+		{name: "field not in layout", csv: "record,type,amount\nheader,H,1\nfooter,T,\n"},
+		{name: "two headers", csv: "record,type\nheader,H\nheader,H\nfooter,T\n"},
+		{name: "no footer", csv: "record,type\nheader,H\n"},
+		{name: "unknown section", csv: "type\nH\n", section: "trailer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var f gofmt256.LayoutFile
+			err := l.ReadCSV(strings.NewReader(tt.csv), &f, tt.section)
+			assert.Error(t, err)
+		})
+	}
+
+	var f gofmt256.LayoutFile
+	err = l.ReadJSON(strings.NewReader(`{"header": {"type": "H"}, "body": [{"type": "D"}], "footer": {"type": "T"}}`), &f, "")
+	assert.Error(t, err)
+}
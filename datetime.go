@@ -0,0 +1,133 @@
+package gofmt256
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	eraCE = "CE"
+	eraBE = "BE"
+
+	// buddhistEraOffset is the number of years the Buddhist era is ahead of
+	// the common era.
+	buddhistEraOffset = 543
+
+	zeroSpaces = "spaces"
+	zeroZeros  = "zeros"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// formatTime renders a time.Time field with the layout of its tag. A zero time
+// is rendered as all spaces, or all zeros with zero=zeros.
+func formatTime(fs FieldStruct, value reflect.Value) (string, error) {
+	t := value.Interface().(time.Time)
+	if t.IsZero() {
+		filler := " "
+		if fs.zero == zeroZeros {
+			filler = "0"
+		}
+		return strings.Repeat(filler, fs.to-fs.from+1), nil
+	}
+
+	if fs.location != nil {
+		t = t.In(fs.location)
+	}
+	data := t.Format(fs.timeLayout)
+	if fs.era == eraBE {
+		return shiftYear(fs.timeLayout, data, buddhistEraOffset)
+	}
+	return data, nil
+}
+
+// setTime reads a time.Time field with the layout of its tag. A value of all
+// spaces, or all zeros with zero=zeros, reads as the zero time.
+func setTime(fs FieldStruct, field reflect.Value, data string) error {
+	if strings.TrimSpace(data) == "" || (fs.zero == zeroZeros && strings.Trim(data, "0") == "") {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+
+	var err error
+	if fs.era == eraBE {
+		data, err = shiftYear(fs.timeLayout, data, -buddhistEraOffset)
+		if err != nil {
+			return err
+		}
+	}
+
+	location := time.UTC
+	if fs.location != nil {
+		location = fs.location
+	}
+	t, err := time.ParseInLocation(fs.timeLayout, data, location)
+	if err != nil {
+		return errors.Wrapf(err, "unable to convert `%s` to `%s`", data, field.Type())
+	}
+	field.Set(reflect.ValueOf(t))
+	return nil
+}
+
+// shiftYear adds years to the year elements, 2006 and 06, of a formatted
+// time. The elements before a year are expected to be fixed width, which is
+// the case for the numeric layouts used in fixed-width files.
+func shiftYear(layout, data string, years int) (string, error) {
+	var shifted strings.Builder
+	last := 0
+	for i := 0; i < len(layout); {
+		width := 0
+		switch {
+		case strings.HasPrefix(layout[i:], "2006"):
+			width = 4
+		case strings.HasPrefix(layout[i:], "06"):
+			width = 2
+		default:
+			i++
+			continue
+		}
+		if i+width > len(data) {
+			return "", errors.Errorf("`%s` does not match layout `%s`", data, layout)
+		}
+
+		year, err := strconv.Atoi(data[i : i+width])
+		if err != nil {
+			return "", errors.Errorf("year `%s` is not a number", data[i:i+width])
+		}
+		year += years
+		if width == 2 {
+			year = (year%100 + 100) % 100
+		}
+		if year < 0 || year > 9999 {
+			return "", errors.Errorf("year %d is out of range", year)
+		}
+
+		shifted.WriteString(data[last:i])
+		shifted.WriteString(fmt.Sprintf("%0*d", width, year))
+		i += width
+		last = i
+	}
+	shifted.WriteString(data[last:])
+	return shifted.String(), nil
+}
+
+func extractEra(value string) (string, error) {
+	switch value {
+	case eraCE, eraBE:
+		return value, nil
+	}
+	return "", errors.Errorf("`era` must be CE or BE, got `%s`", value)
+}
+
+func extractZero(value string) (string, error) {
+	switch value {
+	case zeroSpaces, zeroZeros:
+		return value, nil
+	}
+	return "", errors.Errorf("`zero` must be spaces or zeros, got `%s`", value)
+}
@@ -0,0 +1,49 @@
+package gofmt256_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/100x-fi/gofmt256"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDateTime(t *testing.T) {
+	paymentTime := time.Date(2020, 9, 3, 3, 3, 37, 0, time.UTC)
+	leapDay := time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)
+	record := DateTimeRecord{
+		EffectiveDate: time.Date(2020, 9, 3, 0, 0, 0, 0, time.UTC),
+		PaymentTime:   paymentTime,
+		ThaiDate:      leapDay,
+		ShortThaiDate: leapDay,
+	}
+
+	file, err := gofmt256.New(record, []DateTimeRecord{}, record, gofmt256.WithRecordLength(44)).Build()
+	assert.NoError(t, err)
+	want := "03092020" + "100337" + "29022567" + "670229" + "00000000" + "        " + "\n"
+	assert.Equal(t, want+want, file)
+
+	var header, footer DateTimeRecord
+	var body []DateTimeRecord
+	err = gofmt256.Parse([]byte(file), &header, &body, &footer, gofmt256.WithRecordLength(44))
+	assert.NoError(t, err)
+	assert.True(t, record.EffectiveDate.Equal(header.EffectiveDate))
+	assert.Equal(t, "10:03:37", header.PaymentTime.Format("15:04:05"))
+	assert.Equal(t, "Asia/Bangkok", header.PaymentTime.Location().String())
+	assert.True(t, leapDay.Equal(header.ThaiDate))
+	assert.True(t, leapDay.Equal(header.ShortThaiDate))
+	assert.True(t, header.ZeroDate.IsZero())
+	assert.True(t, header.BlankDate.IsZero())
+}
+
+func TestDateTimeError(t *testing.T) {
+	record := LayoutOnString{EffectiveDate: "03092020"}
+	_, err := gofmt256.New(record, []LayoutOnString{}, record, gofmt256.WithRecordLength(8)).Build()
+	assert.Error(t, err)
+
+	var header, footer DateTimeRecord
+	var body []DateTimeRecord
+	data := "31022020" + "100337" + "29022567" + "670229" + "00000000" + "        " + "\n"
+	err = gofmt256.Parse([]byte(data+data), &header, &body, &footer, gofmt256.WithRecordLength(44))
+	assert.Error(t, err)
+}
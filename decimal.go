@@ -0,0 +1,153 @@
+package gofmt256
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	roundingNone     = ""
+	roundingDown     = "down"
+	roundingUp       = "up"
+	roundingHalfUp   = "half-up"
+	roundingHalfEven = "half-even"
+)
+
+var (
+	ratType             = reflect.TypeOf(&big.Rat{})
+	stringerType        = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+func extractRounding(value string) (string, error) {
+	switch value {
+	case roundingDown, roundingUp, roundingHalfUp, roundingHalfEven:
+		return value, nil
+	}
+	return "", errors.Errorf("`rounding` must be one of down, up, half-up or half-even, got `%s`", value)
+}
+
+// formatDecimal renders an amount with an implied decimal point, e.g. 515.00
+// with decimals=2 becomes 51500. Integer fields are taken to already hold the
+// amount in its smallest unit and are rendered unchanged.
+func formatDecimal(fs FieldStruct, value reflect.Value) (string, error) {
+	var amount *big.Rat
+	switch {
+	case value.Kind() >= reflect.Int && value.Kind() <= reflect.Int64:
+		if value.Int() < 0 {
+			return "", errors.New("negative amount is not supported")
+		}
+		return strconv.FormatInt(value.Int(), 10), nil
+	case value.Kind() >= reflect.Uint && value.Kind() <= reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10), nil
+	case value.Kind() == reflect.Float32 || value.Kind() == reflect.Float64:
+		f := value.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", errors.Errorf("amount `%v` is not a number", f)
+		}
+		amount, _ = new(big.Rat).SetString(strconv.FormatFloat(f, 'f', -1, value.Type().Bits()))
+	case value.Type() == ratType:
+		if value.IsNil() {
+			return "", errors.New("amount is nil")
+		}
+		amount = new(big.Rat).Set(value.Interface().(*big.Rat))
+	case value.Kind() == reflect.String:
+		return scaleDecimal(fs, value.String())
+	case value.Type().Implements(stringerType):
+		if value.Kind() == reflect.Ptr && value.IsNil() {
+			return "", errors.New("amount is nil")
+		}
+		return scaleDecimal(fs, value.Interface().(fmt.Stringer).String())
+	default:
+		return "", errors.Errorf("decimals is not supported for `%s`", value.Type())
+	}
+	return scaleRat(fs, amount)
+}
+
+func scaleDecimal(fs FieldStruct, data string) (string, error) {
+	data = strings.TrimSpace(data)
+	if data == "" {
+		return "0", nil
+	}
+	amount, ok := new(big.Rat).SetString(data)
+	if !ok {
+		return "", errors.Errorf("amount `%s` is not a number", data)
+	}
+	return scaleRat(fs, amount)
+}
+
+func scaleRat(fs FieldStruct, amount *big.Rat) (string, error) {
+	if amount.Sign() < 0 {
+		return "", errors.New("negative amount is not supported")
+	}
+
+	scaled := new(big.Rat).Mul(amount, new(big.Rat).SetInt(pow10(fs.decimals)))
+	if scaled.IsInt() {
+		return scaled.Num().String(), nil
+	}
+	if fs.rounding == roundingNone {
+		return "", errors.Errorf("amount `%s` has more than %d decimals and no rounding is set", amount.FloatString(fs.decimals+2), fs.decimals)
+	}
+
+	q, r := new(big.Int).QuoRem(scaled.Num(), scaled.Denom(), new(big.Int))
+	twice := new(big.Int).Lsh(r, 1)
+	roundUp := false
+	switch fs.rounding {
+	case roundingUp:
+		roundUp = true
+	case roundingHalfUp:
+		roundUp = twice.Cmp(scaled.Denom()) >= 0
+	case roundingHalfEven:
+		c := twice.Cmp(scaled.Denom())
+		roundUp = c > 0 || (c == 0 && q.Bit(0) == 1)
+	}
+	if roundUp {
+		q.Add(q, big.NewInt(1))
+	}
+	return q.String(), nil
+}
+
+// setDecimal reads an amount with an implied decimal point back into field.
+func setDecimal(fs FieldStruct, field reflect.Value, data string) error {
+	data = strings.TrimSpace(data)
+	if data == "" {
+		data = "0"
+	}
+	units, ok := new(big.Int).SetString(data, 10)
+	if !ok || units.Sign() < 0 {
+		return errors.Errorf("amount `%s` is not a number", data)
+	}
+	amount := new(big.Rat).SetFrac(units, pow10(fs.decimals))
+
+	switch {
+	case field.Kind() >= reflect.Int && field.Kind() <= reflect.Int64,
+		field.Kind() >= reflect.Uint && field.Kind() <= reflect.Uint64:
+		return setField(field, units.String())
+	case field.Kind() == reflect.Float32 || field.Kind() == reflect.Float64:
+		f, _ := amount.Float64()
+		field.SetFloat(f)
+	case field.Type() == ratType:
+		field.Set(reflect.ValueOf(amount))
+	case field.Kind() == reflect.String:
+		field.SetString(amount.FloatString(fs.decimals))
+	case field.CanAddr() && field.Addr().Type().Implements(textUnmarshalerType):
+		u := field.Addr().Interface().(encoding.TextUnmarshaler)
+		if err := u.UnmarshalText([]byte(amount.FloatString(fs.decimals))); err != nil {
+			return errors.Wrapf(err, "unable to convert `%s` to `%s`", data, field.Type())
+		}
+	default:
+		return errors.Errorf("decimals is not supported for `%s`", field.Type())
+	}
+	return nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
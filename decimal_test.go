@@ -0,0 +1,95 @@
+package gofmt256_test
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/100x-fi/gofmt256"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecimal(t *testing.T) {
+	record := AmountRecord{
+		Int64Amount:  51500,
+		FloatAmount:  515.004,
+		RatAmount:    big.NewRat(1030, 2),
+		StringAmount: "515.00",
+		MoneyAmount:  Money{value: "515"},
+	}
+
+	file, err := gofmt256.New(record, []AmountRecord{}, record, gofmt256.WithRecordLength(80)).Build()
+	assert.NoError(t, err)
+	want := strings.Repeat("0000000051500", 5) + strings.Repeat(" ", 15) + "\n"
+	assert.Equal(t, want+want, file)
+
+	var header, footer AmountRecord
+	var body []AmountRecord
+	err = gofmt256.Parse([]byte(file), &header, &body, &footer, gofmt256.WithRecordLength(80))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(51500), header.Int64Amount)
+	assert.Equal(t, 515.0, header.FloatAmount)
+	assert.Equal(t, "515/1", header.RatAmount.String())
+	assert.Equal(t, "515.00", header.StringAmount)
+	assert.Equal(t, Money{value: "515.00"}, header.MoneyAmount)
+}
+
+func TestDecimalRounding(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount float64
+		want   string
+	}{
+		{
+			name:   "when amount is exactly half",
+			amount: 1.005,
+			want:   "00100001010010100100\n",
+		},
+		{
+			name:   "when amount is half and quotient is odd",
+			amount: 1.015,
+			want:   "00101001020010200102\n",
+		},
+		{
+			name:   "when amount is below half",
+			amount: 1.011,
+			want:   "00101001020010100101\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := RoundingRecord{Down: tt.amount, Up: tt.amount, HalfUp: tt.amount, HalfEven: tt.amount}
+			got, err := gofmt256.New(record, []RoundingRecord{}, record, gofmt256.WithRecordLength(20)).Build()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want+tt.want, got)
+		})
+	}
+}
+
+func TestDecimalError(t *testing.T) {
+	tests := []struct {
+		name   string
+		record UnroundedRecord
+	}{
+		{
+			name:   "when amount has more decimals than allowed without rounding",
+			record: UnroundedRecord{Amount: 515.001},
+		},
+		{
+			name:   "when amount is negative",
+			record: UnroundedRecord{Amount: -515},
+		},
+		{
+			name:   "when amount overflows the field",
+			record: UnroundedRecord{Amount: 1e9},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := gofmt256.New(tt.record, []UnroundedRecord{}, tt.record, gofmt256.WithRecordLength(20)).Build()
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package gofmt256
+
+import (
+	"bufio"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Decoder reads a format 256 bytes file from an io.Reader one line at a time,
+// so files of any size can be iterated without loading them into memory.
+type Decoder struct {
+	r         *bufio.Reader
+	record    string
+	undefined error // bytes of record that the encoding does not define
+	line      int
+	offset    int64
+	next      int64
+	err       error
+	opts      options
+}
+
+func NewDecoder(r io.Reader, opts ...Option) *Decoder {
+	return newDecoder(r, newOptions(opts))
+}
+
+func newDecoder(r io.Reader, opts options) *Decoder {
+	return &Decoder{
+		r:    bufio.NewReader(r),
+		opts: opts,
+	}
+}
+
+// Next advances to the next line, returning false when there are no more
+// lines or reading failed. Err reports the failure, if any.
+func (d *Decoder) Next() bool {
+	if d.err != nil {
+		return false
+	}
+
+	record, err := d.read()
+	if err != nil {
+		d.err = errors.Wrapf(err, "line %d, offset %d: failed to read", d.line+1, d.next)
+		return false
+	}
+	if record == "" {
+		return false
+	}
+
+	ending := d.opts.ending()
+	d.line++
+	d.offset = d.next
+	d.next += int64(len(record))
+	d.record = strings.TrimSuffix(record, ending)
+	if ending != "" && !d.opts.trailingNewline && len(d.record) < len(record) {
+		if _, err := d.r.Peek(1); err == io.EOF {
+			d.err = errors.Errorf("line %d, offset %d: last line must not end with a line ending", d.line, d.offset)
+			return false
+		}
+	}
+	d.undefined = nil
+	if enc := d.opts.encoding; enc != nil {
+		raw := d.record
+		var invalid int
+		if d.record, invalid = enc.decodeReplacing([]byte(raw)); invalid >= 0 && d.opts.unmappable != UnmappableReplace {
+			_, d.undefined = enc.Decode([]byte(raw))
+		}
+	}
+	return true
+}
+
+// read returns the next line with its line ending, or what there is of it at
+// the end of the input.
+func (d *Decoder) read() (string, error) {
+	ending := d.opts.ending()
+	if ending != "" {
+		record, err := d.r.ReadString(ending[len(ending)-1])
+		if err == io.EOF {
+			err = nil
+		}
+		return record, err
+	}
+
+	size, err := d.opts.blockSize()
+	if err != nil {
+		return "", err
+	}
+	block := make([]byte, size)
+	n, err := io.ReadFull(d.r, block)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return string(block[:n]), err
+}
+
+// RecordType returns the value at the record type position of the current
+// line, which most formats use to tell header, body and footer lines apart.
+// The position defaults to 1 and can be changed with WithRecordTypePosition.
+func (d *Decoder) RecordType() string {
+	from, to := d.opts.recordTypeFrom, d.opts.recordTypeTo
+	if from < 1 || to < from {
+		return ""
+	}
+	recordType, ok := d.opts.unit().ruler(d.record).field(from, to)
+	if !ok {
+		return ""
+	}
+	return recordType
+}
+
+// Decode reads the current line into v, which must be a pointer to struct or
+// implement Record256Unmarshaler.
+func (d *Decoder) Decode(v interface{}) error {
+	if d.line == 0 {
+		return errors.New("Next must be called before Decode")
+	}
+	if d.undefined != nil {
+		return d.fail(lineError(d.record, ErrUnmappable, d.undefined))
+	}
+
+	if u, ok := v.(Record256Unmarshaler); ok {
+		if d.opts.unit().Width(d.record) != d.opts.recordLength {
+			return d.fail(lineError(d.record, ErrLineLength, errors.Errorf("line must be %d %s long", d.opts.recordLength, d.opts.widthUnit)))
+		}
+		if err := u.UnmarshalRecord256([]byte(d.record)); err != nil {
+			return d.fail(lineError(d.record, ErrInvalidValue, err))
+		}
+		return nil
+	}
+
+	if r, ok := v.(*LayoutRecord); ok {
+		schema, err := r.Layout.compile(d.opts)
+		if err != nil {
+			return errors.Wrapf(err, "line %d, offset %d", d.line, d.offset)
+		}
+		value := reflect.New(schema.Type).Elem()
+		err = parseLine(d.record, value, d.opts)
+		r.Values = layoutValues(r.Layout, value)
+		if err != nil {
+			return d.fail(err)
+		}
+		return nil
+	}
+
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return errors.Errorf("line %d, offset %d: output must be pointer to struct", d.line, d.offset)
+	}
+
+	if err := parseLine(d.record, value.Elem(), d.opts); err != nil {
+		return d.fail(err)
+	}
+	return nil
+}
+
+func (d *Decoder) fail(err error) error {
+	if locate(err, ValidationError{Line: d.line, Offset: d.offset}) {
+		return err
+	}
+	return errors.Wrapf(err, "line %d, offset %d", d.line, d.offset)
+}
+
+// DecodeRecord reads the current line into a new value of the struct type
+// registered for its record type with WithRecordType, or into a LayoutRecord
+// when a *Layout is registered or the options come from a FileLayout with a
+// single body layout.
+func (d *Decoder) DecodeRecord() (interface{}, error) {
+	recordType := d.RecordType()
+	l, ok := d.opts.recordLayouts[recordType]
+	if !ok && d.opts.bodyLayout != nil {
+		l, ok = d.opts.bodyLayout, true
+	}
+	if ok {
+		record := LayoutRecord{Layout: l}
+		if err := d.Decode(&record); err != nil {
+			return nil, err
+		}
+		return record, nil
+	}
+	t, ok := d.opts.recordTypes[recordType]
+	if !ok || t == nil || (t.Kind() != reflect.Struct && !reflect.PtrTo(t).Implements(recordUnmarshalerType)) {
+		return nil, errors.Errorf("line %d, offset %d: no struct is registered for record type `%s`", d.line, d.offset, recordType)
+	}
+
+	record := reflect.New(t)
+	if err := d.Decode(record.Interface()); err != nil {
+		return nil, err
+	}
+	return record.Elem().Interface(), nil
+}
+
+// Text returns the current line without its line ending. A line in an
+// encoding is decoded, with bytes that the encoding does not define read as
+// U+FFFD and reported by the error unless WithUnmappable(UnmappableReplace)
+// is given.
+func (d *Decoder) Text() (string, error) {
+	return d.record, d.undefined
+}
+
+// Width returns the width of the current line in the width unit, where a line
+// in a single-byte encoding takes a position for every character.
+func (d *Decoder) Width() int {
+	return d.opts.unit().Width(d.record)
+}
+
+// Line returns the 1-based line number of the current line.
+func (d *Decoder) Line() int {
+	return d.line
+}
+
+// Offset returns the byte offset of the current line from the start of the
+// input.
+func (d *Decoder) Offset() int64 {
+	return d.offset
+}
+
+func (d *Decoder) Err() error {
+	return d.err
+}
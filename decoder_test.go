@@ -0,0 +1,130 @@
+package gofmt256_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/100x-fi/gofmt256"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecoder(t *testing.T) {
+	file, err := gofmt256.New(getSubMerchantReportHeader(), getSubMerchantReportBody(), getSubMerchantReportFooter()).Build()
+	if err != nil {
+		t.Fatalf("gofmt256.Build() err %v", err)
+	}
+
+	var header SubMerchantReportHeader
+	var body []SubMerchantReportBody
+	var footer SubMerchantReportFooter
+	var recordTypes []string
+
+	decoder := gofmt256.NewDecoder(strings.NewReader(file))
+	for decoder.Next() {
+		recordTypes = append(recordTypes, decoder.RecordType())
+		switch decoder.RecordType() {
+		case "H":
+			assert.NoError(t, decoder.Decode(&header))
+		case "D":
+			var record SubMerchantReportBody
+			assert.NoError(t, decoder.Decode(&record))
+			body = append(body, record)
+		case "T":
+			assert.NoError(t, decoder.Decode(&footer))
+		}
+	}
+	assert.NoError(t, decoder.Err())
+
+	wantFooter := getSubMerchantReportFooter()
+	wantFooter.TotalDebitAmount = ""
+	assert.Equal(t, []string{"H", "D", "D", "D", "T"}, recordTypes)
+	assert.Equal(t, getSubMerchantReportHeader(), header)
+	assert.Equal(t, getSubMerchantReportBody(), body)
+	assert.Equal(t, wantFooter, footer)
+}
+
+func TestDecoderError(t *testing.T) {
+	file, err := gofmt256.New(getSubMerchantReportHeader(), getSubMerchantReportBody(), getSubMerchantReportFooter()).Build()
+	if err != nil {
+		t.Fatalf("gofmt256.Build() err %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		line    int
+		decode  interface{}
+		wantErr string
+	}{
+		{
+			name:    "when int field is not a number",
+			data:    file[:257] + "DABCDEF" + file[264:],
+			line:    2,
+			decode:  &SubMerchantReportBody{},
+			wantErr: "line 2, offset 257",
+		},
+		{
+			name:    "when line is shorter than 256 bytes",
+			data:    file[:257+257] + "D\n",
+			line:    3,
+			decode:  &SubMerchantReportBody{},
+			wantErr: "line 3, offset 514",
+		},
+		{
+			name:    "when output is not a pointer",
+			data:    file,
+			line:    1,
+			decode:  SubMerchantReportHeader{},
+			wantErr: "line 1, offset 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := gofmt256.NewDecoder(strings.NewReader(tt.data))
+			for i := 0; i < tt.line; i++ {
+				assert.True(t, decoder.Next())
+			}
+			err := decoder.Decode(tt.decode)
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecoderRecordTypePosition(t *testing.T) {
+	file, err := gofmt256.New(getSubMerchantReportHeader(), getSubMerchantReportBody(), getSubMerchantReportFooter()).Build()
+	if err != nil {
+		t.Fatalf("gofmt256.Build() err %v", err)
+	}
+
+	decoder := gofmt256.NewDecoder(strings.NewReader(file),
+		gofmt256.WithRecordTypePosition(154, 156),
+		gofmt256.WithRecordType("ETH", &SubMerchantReportBody{}),
+	)
+	assert.True(t, decoder.Next())
+	_, err = decoder.DecodeRecord()
+	assert.Error(t, err)
+
+	assert.True(t, decoder.Next())
+	assert.Equal(t, "ETH", decoder.RecordType())
+	record, err := decoder.DecodeRecord()
+	assert.NoError(t, err)
+	assert.Equal(t, getSubMerchantReportBody()[0], record)
+}
+
+func TestDecoderText(t *testing.T) {
+	line, err := gofmt256.EncodingTIS620.Encode("Hบริษัท")
+	if err != nil {
+		t.Fatalf("Encode() err %v", err)
+	}
+
+	decoder := gofmt256.NewDecoder(strings.NewReader(string(line)+"\xFF\n"), gofmt256.WithEncoding(gofmt256.EncodingTIS620))
+	assert.True(t, decoder.Next())
+	text, err := decoder.Text()
+	assert.Equal(t, "Hบริษัท�", text)
+	assert.Error(t, err)
+	assert.Equal(t, 8, decoder.Width())
+	assert.False(t, decoder.Next())
+}
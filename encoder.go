@@ -0,0 +1,302 @@
+package gofmt256
+
+import (
+	"bufio"
+	"io"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	encoderStateInit = iota
+	encoderStateHeader
+	encoderStateFooter
+	encoderStateClosed
+)
+
+// Encoder writes a format 256 bytes file line by line to an io.Writer, so the
+// whole file never has to be held in memory. The header must be written
+// first, followed by any number of records and finally the footer. Records
+// may instead be grouped into batches, each enclosed by WriteBatchHeader and
+// WriteBatchTrailer, in which case no record may be written outside a batch.
+type Encoder struct {
+	w           *bufio.Writer
+	state       int
+	line        int
+	offset      int64
+	inBatch     bool
+	batches     int
+	records     int
+	unbatched   int
+	seq         sequence
+	totals      map[reflect.Type]*tally
+	batchTotals map[reflect.Type]*tally
+	opts        options
+}
+
+func NewEncoder(w io.Writer, opts ...Option) *Encoder {
+	return newEncoder(w, newOptions(opts))
+}
+
+func newEncoder(w io.Writer, opts options) *Encoder {
+	return &Encoder{
+		w:           bufio.NewWriter(w),
+		seq:         newSequence(opts),
+		totals:      make(map[reflect.Type]*tally),
+		batchTotals: make(map[reflect.Type]*tally),
+		opts:        opts,
+	}
+}
+
+// TrackTotals prepares e to compute the aggregate fields, tagged with `agg`,
+// of the footer or batch trailer type of v. Only records written after
+// TrackTotals are counted, and batch trailer totals restart at every batch
+// header. A footer or batch trailer that marshals its own line has no totals
+// to track.
+func (e *Encoder) TrackTotals(v interface{}) error {
+	value := reflect.ValueOf(v)
+	if _, ok := recordMarshaler(value); ok {
+		return nil
+	}
+	if value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return errors.New("totals must be tracked for struct")
+	}
+	value, err := recordValue(value, e.opts)
+	if err != nil {
+		return err
+	}
+	t := value.Type()
+	if _, ok := e.totals[t]; ok {
+		return nil
+	}
+
+	fileTally, err := newTally(t, e.opts)
+	if err != nil {
+		return err
+	}
+	batchTally, err := newTally(t, e.opts)
+	if err != nil {
+		return err
+	}
+	e.totals[t] = fileTally
+	e.batchTotals[t] = batchTally
+	return nil
+}
+
+func (e *Encoder) WriteHeader(header interface{}) error {
+	if e.state != encoderStateInit {
+		return errors.New("header must be written before any record and footer")
+	}
+	e.seq.section()
+	err := e.writeLine(header, SectionHeader)
+	if err != nil && !isValidationError(err) {
+		return err
+	}
+	e.state = encoderStateHeader
+	return err
+}
+
+func (e *Encoder) WriteRecord(record interface{}) error {
+	if e.state != encoderStateHeader {
+		return errors.New("record must be written after header and before footer")
+	}
+	if !e.inBatch && e.batches > 0 {
+		return errors.New("record must be written within a batch")
+	}
+	if !e.inBatch && e.unbatched == 0 {
+		e.seq.section()
+	}
+	err := e.writeLine(record, SectionBody)
+	if err != nil && !isValidationError(err) {
+		return err
+	}
+	e.records++
+	if !e.inBatch {
+		e.unbatched++
+	}
+	return err
+}
+
+func (e *Encoder) WriteBatchHeader(header interface{}) error {
+	if e.state != encoderStateHeader {
+		return errors.New("batch header must be written after header and before footer")
+	}
+	if e.inBatch {
+		return errors.New("batch header must be written after the trailer of the previous batch")
+	}
+	if e.unbatched > 0 {
+		return errors.New("batch header must not follow records outside of a batch")
+	}
+	e.seq.section()
+	err := e.writeLine(header, SectionBatchHeader)
+	if err != nil && !isValidationError(err) {
+		return err
+	}
+	for _, t := range e.batchTotals {
+		t.reset()
+	}
+	e.inBatch = true
+	e.batches++
+	return err
+}
+
+func (e *Encoder) WriteBatchTrailer(trailer interface{}) error {
+	if !e.inBatch {
+		return errors.New("batch trailer must be written after batch header")
+	}
+	err := e.writeLine(trailer, SectionBatchTrailer)
+	if err != nil && !isValidationError(err) {
+		return err
+	}
+	e.inBatch = false
+	return err
+}
+
+func (e *Encoder) WriteFooter(footer interface{}) error {
+	if e.state != encoderStateHeader {
+		return errors.New("footer must be written after header")
+	}
+	if e.inBatch {
+		return errors.New("footer must be written after the last batch trailer")
+	}
+	e.seq.section()
+	err := e.writeLine(footer, SectionFooter)
+	if err != nil && !isValidationError(err) {
+		return err
+	}
+	e.state = encoderStateFooter
+	return err
+}
+
+// Close flushes buffered lines to the underlying writer. It does not close
+// the underlying writer.
+func (e *Encoder) Close() error {
+	if e.state == encoderStateClosed {
+		return nil
+	}
+	if e.state != encoderStateFooter {
+		return errors.New("footer must be written before close")
+	}
+	e.state = encoderStateClosed
+	return e.w.Flush()
+}
+
+// writeLine makes and writes the line of v. A line that fails validation is
+// skipped but still numbered, so that the lines after it are reported at the
+// position they would have in a valid file.
+func (e *Encoder) writeLine(v interface{}, section string) error {
+	value := reflect.ValueOf(v)
+	if m, ok := recordMarshaler(value); ok {
+		line, err := marshalRecord(m, e.opts)
+		if err != nil {
+			return e.fail(err, section)
+		}
+		if err := e.total(reflect.Indirect(value), section); err != nil {
+			return err
+		}
+		return e.write(line, section)
+	}
+
+	if value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return errors.Errorf("%s must be struct", section)
+	}
+	value, err := recordValue(value, e.opts)
+	if err != nil {
+		return e.fail(err, section)
+	}
+
+	ctx := lineContext{
+		opts:    e.opts,
+		section: section,
+		line:    e.line + 1,
+		seq:     e.seq.next,
+	}
+	switch section {
+	case SectionFooter:
+		if t, ok := e.totals[value.Type()]; ok {
+			ctx.totals = t.totals
+		}
+	case SectionBatchTrailer:
+		if t, ok := e.batchTotals[value.Type()]; ok {
+			ctx.totals = t.totals
+		}
+	}
+
+	line, err := makeLine(value, ctx)
+	if err != nil {
+		return e.fail(err, section)
+	}
+	if err := e.total(value, section); err != nil {
+		return err
+	}
+	return e.write(line, section)
+}
+
+// total adds value to the totals of the file and of the batch when it is a
+// body record.
+func (e *Encoder) total(value reflect.Value, section string) error {
+	if section != SectionBody {
+		return nil
+	}
+	for _, t := range e.totals {
+		if err := t.add(value); err != nil {
+			return errors.Wrapf(err, "failed to total %s at line %d", section, e.line+1)
+		}
+	}
+	for _, t := range e.batchTotals {
+		if err := t.add(value); err != nil {
+			return errors.Wrapf(err, "failed to total %s at line %d", section, e.line+1)
+		}
+	}
+	return nil
+}
+
+func (e *Encoder) write(line string, section string) error {
+	line, err := encodeLine(line, e.opts)
+	if err != nil {
+		return e.fail(err, section)
+	}
+	ending := e.opts.ending()
+	if ending == "" {
+		if _, err := e.opts.blockSize(); err != nil {
+			return errors.Wrapf(err, "failed to write %s at line %d", section, e.line+1)
+		}
+	}
+	if section != SectionFooter || e.opts.trailingNewline {
+		line += ending
+	}
+	if _, err := e.w.WriteString(line); err != nil {
+		return errors.Wrapf(err, "failed to write %s at line %d", section, e.line+1)
+	}
+	e.line++
+	e.offset += int64(len(line))
+	e.seq.next++
+	return nil
+}
+
+func (e *Encoder) fail(err error, section string) error {
+	index := 0
+	switch section {
+	case SectionBody:
+		index = e.records
+	case SectionBatchHeader:
+		index = e.batches
+	case SectionBatchTrailer:
+		index = e.batches - 1
+	}
+	if !locate(err, ValidationError{Section: section, Index: index, Line: e.line + 1, Offset: e.offset}) {
+		return errors.Wrapf(err, "failed to make %s at line %d", section, e.line+1)
+	}
+
+	e.line++
+	e.offset += int64(e.opts.recordLength + len(e.opts.ending()))
+	e.seq.next++
+	return err
+}
@@ -0,0 +1,186 @@
+package gofmt256_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/100x-fi/gofmt256"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncoder(t *testing.T) {
+	want, err := gofmt256.New(getSubMerchantReportHeader(), getSubMerchantReportBody(), getSubMerchantReportFooter()).Build()
+	if err != nil {
+		t.Fatalf("gofmt256.Build() err %v", err)
+	}
+
+	var buf bytes.Buffer
+	encoder := gofmt256.NewEncoder(&buf)
+	assert.NoError(t, encoder.WriteHeader(getSubMerchantReportHeader()))
+	for _, record := range getSubMerchantReportBody() {
+		assert.NoError(t, encoder.WriteRecord(record))
+	}
+	assert.NoError(t, encoder.WriteFooter(getSubMerchantReportFooter()))
+	assert.NoError(t, encoder.Close())
+	assert.Equal(t, want, buf.String())
+}
+
+func TestEncoderOrdering(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(e *gofmt256.Encoder) error
+	}{
+		{
+			name: "when record is written before header",
+			write: func(e *gofmt256.Encoder) error {
+				return e.WriteRecord(getSubMerchantReportBody()[0])
+			},
+		},
+		{
+			name: "when footer is written before header",
+			write: func(e *gofmt256.Encoder) error {
+				return e.WriteFooter(getSubMerchantReportFooter())
+			},
+		},
+		{
+			name: "when header is written twice",
+			write: func(e *gofmt256.Encoder) error {
+				if err := e.WriteHeader(getSubMerchantReportHeader()); err != nil {
+					return err
+				}
+				return e.WriteHeader(getSubMerchantReportHeader())
+			},
+		},
+		{
+			name: "when record is written after footer",
+			write: func(e *gofmt256.Encoder) error {
+				if err := e.WriteHeader(getSubMerchantReportHeader()); err != nil {
+					return err
+				}
+				if err := e.WriteFooter(getSubMerchantReportFooter()); err != nil {
+					return err
+				}
+				return e.WriteRecord(getSubMerchantReportBody()[0])
+			},
+		},
+		{
+			name: "when close is called before footer",
+			write: func(e *gofmt256.Encoder) error {
+				if err := e.WriteHeader(getSubMerchantReportHeader()); err != nil {
+					return err
+				}
+				return e.Close()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := tt.write(gofmt256.NewEncoder(&buf))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestEncoderLineNumber(t *testing.T) {
+	var buf bytes.Buffer
+	encoder := gofmt256.NewEncoder(&buf)
+	assert.NoError(t, encoder.WriteHeader(getSubMerchantReportHeader()))
+	assert.NoError(t, encoder.WriteRecord(getSubMerchantReportBody()[0]))
+
+	record := getSubMerchantReportBody()[1]
+	record.RecordType = "DD"
+	err := encoder.WriteRecord(record)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "line 3")
+	}
+}
+
+func TestEncoderBatchOrdering(t *testing.T) {
+	batchHeader := SubMerchantReportBatchHeader{RecordType: "B", BatchNo: 1}
+	batchTrailer := SubMerchantReportBatchTrailer{RecordType: "C", BatchNo: 1}
+	record := getSubMerchantReportBody()[0]
+
+	tests := []struct {
+		name  string
+		write func(e *gofmt256.Encoder) error
+	}{
+		{
+			name: "when batch trailer is written without batch header",
+			write: func(e *gofmt256.Encoder) error {
+				return e.WriteBatchTrailer(batchTrailer)
+			},
+		},
+		{
+			name: "when batch header is written within a batch",
+			write: func(e *gofmt256.Encoder) error {
+				if err := e.WriteBatchHeader(batchHeader); err != nil {
+					return err
+				}
+				return e.WriteBatchHeader(batchHeader)
+			},
+		},
+		{
+			name: "when record is written between batches",
+			write: func(e *gofmt256.Encoder) error {
+				if err := e.WriteBatchHeader(batchHeader); err != nil {
+					return err
+				}
+				if err := e.WriteBatchTrailer(batchTrailer); err != nil {
+					return err
+				}
+				return e.WriteRecord(record)
+			},
+		},
+		{
+			name: "when batch header follows records outside of a batch",
+			write: func(e *gofmt256.Encoder) error {
+				if err := e.WriteRecord(record); err != nil {
+					return err
+				}
+				return e.WriteBatchHeader(batchHeader)
+			},
+		},
+		{
+			name: "when footer is written within a batch",
+			write: func(e *gofmt256.Encoder) error {
+				if err := e.WriteBatchHeader(batchHeader); err != nil {
+					return err
+				}
+				return e.WriteFooter(getSubMerchantReportFooter())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			encoder := gofmt256.NewEncoder(&buf)
+			assert.NoError(t, encoder.WriteHeader(getSubMerchantReportHeader()))
+			assert.Error(t, tt.write(encoder))
+		})
+	}
+}
+
+func TestEncoderTrackTotals(t *testing.T) {
+	want, err := gofmt256.New(getSubMerchantReportHeader(), getSubMerchantReportBody(), getSubMerchantReportFooter()).Build()
+	if err != nil {
+		t.Fatalf("gofmt256.Build() err %v", err)
+	}
+
+	var buf bytes.Buffer
+	encoder := gofmt256.NewEncoder(&buf)
+	assert.NoError(t, encoder.TrackTotals(SubMerchantReportAggregatedFooter{}))
+	assert.NoError(t, encoder.WriteHeader(getSubMerchantReportHeader()))
+	for _, record := range getSubMerchantReportBody() {
+		assert.NoError(t, encoder.WriteRecord(record))
+	}
+	assert.NoError(t, encoder.WriteFooter(getSubMerchantReportAggregatedFooter()))
+	assert.NoError(t, encoder.Close())
+	assert.Equal(t, want, buf.String())
+
+	encoder = gofmt256.NewEncoder(&buf)
+	assert.NoError(t, encoder.WriteHeader(getSubMerchantReportHeader()))
+	assert.Error(t, encoder.WriteFooter(getSubMerchantReportAggregatedFooter()))
+}
@@ -0,0 +1,254 @@
+package gofmt256
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// Encoding is a single-byte character set that files are written in and
+// read from instead of UTF-8, set with WithEncoding. Every character takes
+// one byte, so field widths in bytes are counted in characters.
+type Encoding struct {
+	name   string
+	decode [256]rune
+	encode map[rune]byte
+}
+
+// Unmappable decides what happens to characters that an Encoding cannot
+// encode, or bytes that it does not define.
+type Unmappable string
+
+const (
+	// UnmappableError fails the field or line. It is the default.
+	UnmappableError Unmappable = "error"
+	// UnmappableReplace writes `?` instead of characters that cannot be
+	// encoded and reads U+FFFD instead of bytes that are not defined.
+	UnmappableReplace Unmappable = "replace"
+)
+
+const (
+	unmapped    = -1
+	replacement = '?'
+)
+
+// Encodings available to WithEncoding.
+var (
+	EncodingASCII      = newEncoding("ASCII", asciiTable())
+	EncodingTIS620     = newEncoding("TIS-620", tis620Table())
+	EncodingWindows874 = newEncoding("Windows-874", windows874Table())
+	EncodingCP037      = newEncoding("CP037", cp037Table)
+)
+
+var encodingNames = map[string]*Encoding{
+	"ascii":       EncodingASCII,
+	"us-ascii":    EncodingASCII,
+	"tis-620":     EncodingTIS620,
+	"tis620":      EncodingTIS620,
+	"windows-874": EncodingWindows874,
+	"cp874":       EncodingWindows874,
+	"cp037":       EncodingCP037,
+	"ibm037":      EncodingCP037,
+}
+
+// WithEncoding sets the character set that files are written in and read
+// from. It defaults to UTF-8, which is written as is.
+func WithEncoding(enc *Encoding) Option {
+	return func(o *options) {
+		o.encoding = enc
+	}
+}
+
+// WithUnmappable sets what happens to characters that the encoding cannot
+// encode, or bytes that it does not define. It defaults to UnmappableError.
+func WithUnmappable(unmappable Unmappable) Option {
+	return func(o *options) {
+		o.unmappable = unmappable
+	}
+}
+
+// LookupEncoding returns the encoding of a name such as tis-620,
+// windows-874, cp037 or ascii, ignoring case. It returns nil for utf-8 or an
+// empty name.
+func LookupEncoding(name string) (*Encoding, error) {
+	name = strings.ToLower(name)
+	if name == "" || name == "utf-8" || name == "utf8" {
+		return nil, nil
+	}
+	enc, ok := encodingNames[name]
+	if !ok {
+		return nil, errors.Errorf("unknown encoding `%s`", name)
+	}
+	return enc, nil
+}
+
+func newEncoding(name string, decode [256]rune) *Encoding {
+	e := &Encoding{name: name, decode: decode, encode: make(map[rune]byte)}
+	for b, r := range decode {
+		if r != unmapped {
+			e.encode[r] = byte(b)
+		}
+	}
+	return e
+}
+
+func (e *Encoding) String() string {
+	return e.name
+}
+
+// Encode returns s in e, or an error naming the first character of s that e
+// cannot encode.
+func (e *Encoding) Encode(s string) ([]byte, error) {
+	data := make([]byte, 0, len(s))
+	for _, r := range s {
+		b, ok := e.encode[r]
+		if !ok {
+			return nil, errors.Errorf("character `%c` (%U) cannot be encoded in %s", r, r, e.name)
+		}
+		data = append(data, b)
+	}
+	return data, nil
+}
+
+// Decode returns data read from e, or an error naming the first byte of
+// data that e does not define.
+func (e *Encoding) Decode(data []byte) (string, error) {
+	s, invalid := e.decodeReplacing(data)
+	if invalid >= 0 {
+		return "", errors.Errorf("byte 0x%02X at %d is not defined in %s", data[invalid], invalid, e.name)
+	}
+	return s, nil
+}
+
+// replace returns s with the characters that e cannot encode replaced.
+func (e *Encoding) replace(s string) string {
+	return strings.Map(func(r rune) rune {
+		if _, ok := e.encode[r]; !ok {
+			return replacement
+		}
+		return r
+	}, s)
+}
+
+// decodeReplacing decodes data, replacing bytes that e does not define with
+// U+FFFD, and returns the index of the first of them or -1.
+func (e *Encoding) decodeReplacing(data []byte) (string, int) {
+	invalid := -1
+	var sb strings.Builder
+	sb.Grow(len(data))
+	for i, b := range data {
+		r := e.decode[b]
+		if r == unmapped {
+			if invalid < 0 {
+				invalid = i
+			}
+			r = utf8.RuneError
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String(), invalid
+}
+
+// encodeData checks that data of a field can be encoded, replacing the
+// characters that cannot be when opts allow it.
+func encodeData(fs FieldStruct, data string, opts options) (string, error) {
+	enc := opts.encoding
+	if enc == nil {
+		return data, nil
+	}
+	if opts.unmappable == UnmappableReplace {
+		return enc.replace(data), nil
+	}
+	if _, err := enc.Encode(data); err != nil {
+		return "", fieldError(fs, data, ErrUnmappable, err)
+	}
+	return data, nil
+}
+
+// encodeLine returns line in the encoding of opts.
+func encodeLine(line string, opts options) (string, error) {
+	enc := opts.encoding
+	if enc == nil {
+		return line, nil
+	}
+	if opts.unmappable == UnmappableReplace {
+		line = enc.replace(line)
+	}
+	data, err := enc.Encode(line)
+	if err != nil {
+		return "", lineError(line, ErrUnmappable, err)
+	}
+	return string(data), nil
+}
+
+// unit returns the width unit of opts, which counts characters for bytes
+// when lines are encoded in a single-byte encoding.
+func (o options) unit() WidthUnit {
+	if o.encoding != nil && o.widthUnit.isBytes() {
+		return WidthRunes
+	}
+	return o.widthUnit
+}
+
+func asciiTable() [256]rune {
+	var t [256]rune
+	for i := range t {
+		t[i] = unmapped
+		if i < 0x80 {
+			t[i] = rune(i)
+		}
+	}
+	return t
+}
+
+func tis620Table() [256]rune {
+	var t [256]rune
+	for i := range t {
+		switch {
+		case i < 0xA0:
+			t[i] = rune(i)
+		case i >= 0xA1 && i <= 0xDA:
+			t[i] = rune(0x0E01 + i - 0xA1)
+		case i >= 0xDF && i <= 0xFB:
+			t[i] = rune(0x0E3F + i - 0xDF)
+		default:
+			t[i] = unmapped
+		}
+	}
+	return t
+}
+
+func windows874Table() [256]rune {
+	t := tis620Table()
+	for i := 0x80; i < 0xA0; i++ {
+		t[i] = unmapped
+	}
+	t[0x80] = 0x20AC
+	t[0x85] = 0x2026
+	t[0x91], t[0x92], t[0x93], t[0x94] = 0x2018, 0x2019, 0x201C, 0x201D
+	t[0x95], t[0x96], t[0x97] = 0x2022, 0x2013, 0x2014
+	t[0xA0] = 0x00A0
+	return t
+}
+
+// cp037Table is EBCDIC code page 037, used by IBM mainframes in the US and
+// Canada.
+var cp037Table = [256]rune{
+	0x0000, 0x0001, 0x0002, 0x0003, 0x009C, 0x0009, 0x0086, 0x007F, 0x0097, 0x008D, 0x008E, 0x000B, 0x000C, 0x000D, 0x000E, 0x000F,
+	0x0010, 0x0011, 0x0012, 0x0013, 0x009D, 0x0085, 0x0008, 0x0087, 0x0018, 0x0019, 0x0092, 0x008F, 0x001C, 0x001D, 0x001E, 0x001F,
+	0x0080, 0x0081, 0x0082, 0x0083, 0x0084, 0x000A, 0x0017, 0x001B, 0x0088, 0x0089, 0x008A, 0x008B, 0x008C, 0x0005, 0x0006, 0x0007,
+	0x0090, 0x0091, 0x0016, 0x0093, 0x0094, 0x0095, 0x0096, 0x0004, 0x0098, 0x0099, 0x009A, 0x009B, 0x0014, 0x0015, 0x009E, 0x001A,
+	0x0020, 0x00A0, 0x00E2, 0x00E4, 0x00E0, 0x00E1, 0x00E3, 0x00E5, 0x00E7, 0x00F1, 0x00A2, 0x002E, 0x003C, 0x0028, 0x002B, 0x007C,
+	0x0026, 0x00E9, 0x00EA, 0x00EB, 0x00E8, 0x00ED, 0x00EE, 0x00EF, 0x00EC, 0x00DF, 0x0021, 0x0024, 0x002A, 0x0029, 0x003B, 0x00AC,
+	0x002D, 0x002F, 0x00C2, 0x00C4, 0x00C0, 0x00C1, 0x00C3, 0x00C5, 0x00C7, 0x00D1, 0x00A6, 0x002C, 0x0025, 0x005F, 0x003E, 0x003F,
+	0x00F8, 0x00C9, 0x00CA, 0x00CB, 0x00C8, 0x00CD, 0x00CE, 0x00CF, 0x00CC, 0x0060, 0x003A, 0x0023, 0x0040, 0x0027, 0x003D, 0x0022,
+	0x00D8, 0x0061, 0x0062, 0x0063, 0x0064, 0x0065, 0x0066, 0x0067, 0x0068, 0x0069, 0x00AB, 0x00BB, 0x00F0, 0x00FD, 0x00FE, 0x00B1,
+	0x00B0, 0x006A, 0x006B, 0x006C, 0x006D, 0x006E, 0x006F, 0x0070, 0x0071, 0x0072, 0x00AA, 0x00BA, 0x00E6, 0x00B8, 0x00C6, 0x00A4,
+	0x00B5, 0x007E, 0x0073, 0x0074, 0x0075, 0x0076, 0x0077, 0x0078, 0x0079, 0x007A, 0x00A1, 0x00BF, 0x00D0, 0x00DD, 0x00DE, 0x00AE,
+	0x005E, 0x00A3, 0x00A5, 0x00B7, 0x00A9, 0x00A7, 0x00B6, 0x00BC, 0x00BD, 0x00BE, 0x005B, 0x005D, 0x00AF, 0x00A8, 0x00B4, 0x00D7,
+	0x007B, 0x0041, 0x0042, 0x0043, 0x0044, 0x0045, 0x0046, 0x0047, 0x0048, 0x0049, 0x00AD, 0x00F4, 0x00F6, 0x00F2, 0x00F3, 0x00F5,
+	0x007D, 0x004A, 0x004B, 0x004C, 0x004D, 0x004E, 0x004F, 0x0050, 0x0051, 0x0052, 0x00B9, 0x00FB, 0x00FC, 0x00F9, 0x00FA, 0x00FF,
+	0x005C, 0x00F7, 0x0053, 0x0054, 0x0055, 0x0056, 0x0057, 0x0058, 0x0059, 0x005A, 0x00B2, 0x00D4, 0x00D6, 0x00D2, 0x00D3, 0x00D5,
+	0x0030, 0x0031, 0x0032, 0x0033, 0x0034, 0x0035, 0x0036, 0x0037, 0x0038, 0x0039, 0x00B3, 0x00DB, 0x00DC, 0x00D9, 0x00DA, 0x009F,
+}
@@ -0,0 +1,88 @@
+package gofmt256_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/100x-fi/gofmt256"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncoding(t *testing.T) {
+	record := OverflowRecord{RecordType: "D", Name: "สมศักดิ์", Reference: "123", Note: "ok"}
+	opts := []gofmt256.Option{gofmt256.WithRecordLength(20), gofmt256.WithEncoding(gofmt256.EncodingTIS620)}
+
+	got, err := gofmt256.New(record, []OverflowRecord{}, record, opts...).Build()
+	assert.NoError(t, err)
+	line := "D\xca\xc1\xc8\xd1\xa1\xb4\xd4\xec123   ok   \n"
+	assert.Equal(t, line+line, got)
+
+	var header, footer OverflowRecord
+	var body []OverflowRecord
+	err = gofmt256.Parse([]byte(got), &header, &body, &footer, opts...)
+	assert.NoError(t, err)
+	assert.Equal(t, record, header)
+	assert.Equal(t, record, footer)
+}
+
+func TestEncodingEBCDIC(t *testing.T) {
+	record := OverflowRecord{RecordType: "D", Name: "123"}
+	opts := []gofmt256.Option{gofmt256.WithRecordLength(20), gofmt256.WithEncoding(gofmt256.EncodingCP037)}
+
+	got, err := gofmt256.New(record, []OverflowRecord{}, record, opts...).Build()
+	assert.NoError(t, err)
+	assert.Len(t, got, 42)
+	assert.Equal(t, "\xc4\xf1\xf2\xf3", got[:4])
+	assert.Equal(t, byte(0x25), got[20])
+
+	var header, footer OverflowRecord
+	var body []OverflowRecord
+	err = gofmt256.Parse([]byte(got), &header, &body, &footer, opts...)
+	assert.NoError(t, err)
+	assert.Equal(t, record, header)
+}
+
+func TestEncodingUnmappable(t *testing.T) {
+	record := OverflowRecord{RecordType: "D", Name: "株式会社", Note: "ok"}
+	opts := []gofmt256.Option{gofmt256.WithRecordLength(20), gofmt256.WithEncoding(gofmt256.EncodingASCII)}
+
+	_, err := gofmt256.New(record, []OverflowRecord{}, record, opts...).Build()
+	assert.True(t, errors.Is(err, gofmt256.ErrUnmappable))
+	assert.EqualError(t, err, "header, line 1, offset 0: [Name] from 2 to 9: character `株` (U+682A) cannot be encoded in ASCII")
+
+	got, err := gofmt256.New(record, []OverflowRecord{}, record,
+		append(opts, gofmt256.WithUnmappable(gofmt256.UnmappableReplace))...).Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "D????          ok   \nD????          ok   \n", got)
+
+	var header, footer OverflowRecord
+	var body []OverflowRecord
+	data := []byte("D\xff                  \nD                   \n")
+	err = gofmt256.Parse(data, &header, &body, &footer, opts...)
+	assert.True(t, errors.Is(err, gofmt256.ErrUnmappable))
+	assert.EqualError(t, err, "header, line 1, offset 0: byte 0xFF at 1 is not defined in ASCII")
+
+	err = gofmt256.Parse(data, &header, &body, &footer, append(opts, gofmt256.WithUnmappable(gofmt256.UnmappableReplace))...)
+	assert.NoError(t, err)
+	assert.Equal(t, "�", header.Name)
+}
+
+func TestLookupEncoding(t *testing.T) {
+	enc, err := gofmt256.LookupEncoding("TIS-620")
+	assert.NoError(t, err)
+	assert.Same(t, gofmt256.EncodingTIS620, enc)
+	enc, err = gofmt256.LookupEncoding("utf-8")
+	assert.NoError(t, err)
+	assert.Nil(t, enc)
+	_, err = gofmt256.LookupEncoding("latin-1")
+	assert.Error(t, err)
+
+	data, err := gofmt256.EncodingWindows874.Encode("€ ๑")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x80, ' ', 0xf1}, data)
+	_, err = gofmt256.EncodingTIS620.Encode("€")
+	assert.Error(t, err)
+	s, err := gofmt256.EncodingCP037.Decode([]byte{0xc8, 0x85, 0x93, 0x93, 0x96})
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello", s)
+}
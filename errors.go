@@ -0,0 +1,190 @@
+package gofmt256
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Sections of a file, as reported by ValidationError and Warning.
+const (
+	SectionHeader       = "header"
+	SectionBody         = "body"
+	SectionBatchHeader  = "batch header"
+	SectionBatchTrailer = "batch trailer"
+	SectionFooter       = "footer"
+)
+
+// Sentinel errors for the kinds of ValidationError, usable with errors.Is.
+var (
+	ErrTooLong           = errors.New("data is longer than length")
+	ErrInvalidValue      = errors.New("invalid value")
+	ErrLineLength        = errors.New("invalid line length")
+	ErrSequenceGap       = errors.New("sequence gap")
+	ErrAggregateMismatch = errors.New("aggregate mismatch")
+	ErrPadding           = errors.New("invalid padding")
+	ErrUnmappable        = errors.New("unmappable character")
+)
+
+// ValidationError reports a record or field that cannot be built or parsed.
+// Index is the 0-based position of the record among the body records, or of
+// the batch for batch headers and trailers; it is 0 for the header and the
+// footer. Field, From and To are empty for errors about a whole line. Value
+// is the data of the field or line, and Err is one of the sentinel errors.
+type ValidationError struct {
+	Section string
+	Index   int
+	Line    int
+	Offset  int64
+	Field   string
+	From    int
+	To      int
+	Value   string
+	Reason  string
+	Err     error
+
+	cause error
+}
+
+func fieldError(fs FieldStruct, value string, kind, cause error) *ValidationError {
+	err := lineError(value, kind, cause)
+	err.Field, err.From, err.To = fs.Name, fs.from, fs.to
+	return err
+}
+
+func lineError(value string, kind, cause error) *ValidationError {
+	reason := kind.Error()
+	if cause != nil {
+		reason = cause.Error()
+	}
+	return &ValidationError{
+		Value:  value,
+		Reason: reason,
+		Err:    kind,
+		cause:  cause,
+	}
+}
+
+func (e *ValidationError) Error() string {
+	var where []string
+	switch e.Section {
+	case "":
+	case SectionBody, SectionBatchHeader, SectionBatchTrailer:
+		where = append(where, fmt.Sprintf("%s[%d]", e.Section, e.Index))
+	default:
+		where = append(where, e.Section)
+	}
+	if e.Line > 0 {
+		where = append(where, fmt.Sprintf("line %d, offset %d", e.Line, e.Offset))
+	}
+
+	msg := e.Reason
+	if e.Field != "" {
+		msg = fmt.Sprintf("[%s] from %d to %d: %s", e.Field, e.From, e.To, e.Reason)
+	}
+	if len(where) == 0 {
+		return msg
+	}
+	return strings.Join(where, ", ") + ": " + msg
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == e.Err
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.cause
+}
+
+// MultiError holds every validation error of a file. It is returned when
+// WithCollectAllErrors is set. errors.Is and errors.As match any of them.
+type MultiError []error
+
+func (m MultiError) Error() string {
+	if len(m) == 1 {
+		return m[0].Error()
+	}
+	msgs := make([]string, len(m))
+	for i, err := range m {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d errors occurred: %s", len(m), strings.Join(msgs, "; "))
+}
+
+func (m MultiError) Is(target error) bool {
+	for _, err := range m {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m MultiError) As(target interface{}) bool {
+	for _, err := range m {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m MultiError) append(err error) MultiError {
+	var more MultiError
+	if errors.As(err, &more) {
+		return append(m, more...)
+	}
+	return append(m, err)
+}
+
+func isValidationError(err error) bool {
+	var v *ValidationError
+	return errors.As(err, &v)
+}
+
+// locate fills in where the validation errors in err happened, keeping what
+// is already known, and reports whether err is a validation error.
+func locate(err error, at ValidationError) bool {
+	var m MultiError
+	if errors.As(err, &m) {
+		for _, err := range m {
+			locate(err, at)
+		}
+		return true
+	}
+	var v *ValidationError
+	if !errors.As(err, &v) {
+		return false
+	}
+	if v.Section == "" {
+		v.Section, v.Index = at.Section, at.Index
+	}
+	if v.Line == 0 {
+		v.Line, v.Offset = at.Line, at.Offset
+	}
+	return true
+}
+
+// collector gathers validation errors instead of failing on the first one
+// when WithCollectAllErrors is set.
+type collector struct {
+	all  bool
+	errs MultiError
+}
+
+// check returns err unless it is a validation error that is collected.
+func (c *collector) check(err error) error {
+	if err == nil || !c.all || !isValidationError(err) {
+		return err
+	}
+	c.errs = c.errs.append(err)
+	return nil
+}
+
+func (c *collector) err() error {
+	if len(c.errs) == 0 {
+		return nil
+	}
+	return c.errs
+}
@@ -0,0 +1,82 @@
+package gofmt256_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/100x-fi/gofmt256"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidationError(t *testing.T) {
+	header := OverflowRecord{RecordType: "H"}
+	body := []OverflowRecord{
+		{RecordType: "D", Name: "Somchai"},
+		{RecordType: "D", Name: "Somchai Jaidee"},
+	}
+	footer := OverflowRecord{RecordType: "T"}
+
+	_, err := gofmt256.New(header, body, footer, gofmt256.WithRecordLength(20)).Build()
+	assert.True(t, errors.Is(err, gofmt256.ErrTooLong))
+
+	var validationErr *gofmt256.ValidationError
+	if assert.True(t, errors.As(err, &validationErr)) {
+		assert.Equal(t, gofmt256.SectionBody, validationErr.Section)
+		assert.Equal(t, 1, validationErr.Index)
+		assert.Equal(t, 3, validationErr.Line)
+		assert.Equal(t, "Name", validationErr.Field)
+		assert.Equal(t, 2, validationErr.From)
+		assert.Equal(t, 9, validationErr.To)
+		assert.Equal(t, "Somchai Jaidee", validationErr.Value)
+		assert.Equal(t, "body[1], line 3, offset 42: [Name] from 2 to 9: data is longer than length", err.Error())
+	}
+}
+
+func TestCollectAllErrors(t *testing.T) {
+	header := OverflowRecord{RecordType: "H", Note: "header"}
+	body := []OverflowRecord{
+		{RecordType: "D", Name: "Somchai Jaidee", Note: "too long"},
+		{RecordType: "D", Name: "Somchai"},
+	}
+	footer := OverflowRecord{RecordType: "TT"}
+
+	_, err := gofmt256.New(header, body, footer, gofmt256.WithRecordLength(20), gofmt256.WithCollectAllErrors()).Build()
+	var multiErr gofmt256.MultiError
+	if assert.True(t, errors.As(err, &multiErr)) && assert.Len(t, multiErr, 4) {
+		var got []string
+		for _, err := range multiErr {
+			var v *gofmt256.ValidationError
+			assert.True(t, errors.As(err, &v))
+			got = append(got, v.Section+" "+v.Field)
+		}
+		assert.Equal(t, []string{"header Note", "body Name", "body Note", "footer RecordType"}, got)
+	}
+	assert.True(t, errors.Is(err, gofmt256.ErrTooLong))
+}
+
+func TestParseCollectAllErrors(t *testing.T) {
+	data := "H000001             \n" +
+		"HABCDEF             \n" +
+		"H000004             \n" +
+		"T000005             \n"
+
+	var header, footer SequencedRecord
+	var body []SequencedRecord
+	err := gofmt256.Parse([]byte(data), &header, &body, &footer, gofmt256.WithRecordLength(20))
+	assert.True(t, errors.Is(err, gofmt256.ErrInvalidValue))
+
+	err = gofmt256.Parse([]byte(data), &header, &body, &footer, gofmt256.WithRecordLength(20), gofmt256.WithCollectAllErrors())
+	var multiErr gofmt256.MultiError
+	if assert.True(t, errors.As(err, &multiErr)) && assert.Len(t, multiErr, 3) {
+		assert.True(t, errors.Is(multiErr[0], gofmt256.ErrInvalidValue))
+		assert.True(t, errors.Is(multiErr[1], gofmt256.ErrSequenceGap))
+		assert.True(t, errors.Is(multiErr[2], gofmt256.ErrSequenceGap))
+
+		var v *gofmt256.ValidationError
+		assert.True(t, errors.As(multiErr[1], &v))
+		assert.Equal(t, gofmt256.SectionBody, v.Section)
+		assert.Equal(t, 1, v.Index)
+		assert.Equal(t, 3, v.Line)
+		assert.Equal(t, int64(42), v.Offset)
+	}
+}
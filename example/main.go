@@ -134,5 +134,5 @@ func main() {
 		panic(err)
 	}
 
-	fmt.Println(out)
+	fmt.Print(out)
 }
@@ -0,0 +1,53 @@
+package gofmt256
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// fillerName names the ranges of a layout that no field covers.
+const fillerName = "filler"
+
+// WithFiller fills the positions that no field covers with filler, a single
+// character such as " " or "0", so that layouts need not declare spare
+// fields. Without it, such positions fail the layout, which catches fields
+// whose positions are mistyped. Filler ranges are written with filler and
+// ignored when parsing unless WithStrictFiller is given.
+func WithFiller(filler string) Option {
+	return func(o *options) {
+		o.filler = filler
+	}
+}
+
+// WithStrictFiller makes parsing check that filler ranges hold nothing but
+// the filler character, reporting them with ErrPadding otherwise.
+func WithStrictFiller() Option {
+	return func(o *options) {
+		o.strictFiller = true
+	}
+}
+
+// checkFiller rejects a filler of opts that does not take exactly one
+// position, which would make lines longer or shorter than the record length.
+func checkFiller(opts options) error {
+	if opts.filler != "" && opts.unit().Width(opts.filler) != 1 {
+		return errors.Errorf("filler must take one position, got `%s`", opts.filler)
+	}
+	return nil
+}
+
+// fillerData returns the filler of fs, a filler range.
+func fillerData(fs FieldStruct, opts options) string {
+	return strings.Repeat(opts.filler, fs.to-fs.from+1)
+}
+
+// fillerProblem reports data of a filler range that is not made of the filler
+// character when opts ask for it.
+func fillerProblem(data string, opts options) string {
+	if !opts.strictFiller || strings.Trim(data, opts.filler) == "" {
+		return ""
+	}
+	return fmt.Sprintf("filler must be `%s`", opts.filler)
+}
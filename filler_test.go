@@ -0,0 +1,51 @@
+package gofmt256_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/100x-fi/gofmt256"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFiller(t *testing.T) {
+	record := GapRecord{RecordType: "D", Name: "name", Amount: 12}
+
+	_, err := gofmt256.New(record, []GapRecord{}, record, gofmt256.WithRecordLength(20)).Build()
+	assert.EqualError(t, err, "failed to make footer: failed to validate slot in 20 length: from to not fill 20 bytes, 10 to 13 are not covered")
+
+	_, err = gofmt256.New(record, []GapRecord{}, record, gofmt256.WithRecordLength(20), gofmt256.WithFiller("ab")).Build()
+	assert.EqualError(t, err, "failed to make footer: filler must take one position, got `ab`")
+	_, err = gofmt256.Compile(GapRecord{}, gofmt256.WithRecordLength(20), gofmt256.WithFiller("ศ"))
+	assert.EqualError(t, err, "filler must take one position, got `ศ`")
+
+	opts := []gofmt256.Option{gofmt256.WithRecordLength(20), gofmt256.WithFiller("0")}
+	got, err := gofmt256.New(record, []GapRecord{}, record, opts...).Build()
+	assert.NoError(t, err)
+	line := "Dname    00000012000"
+	assert.Equal(t, line+"\n"+line+"\n", got)
+
+	schema, err := gofmt256.Compile(GapRecord{}, opts...)
+	assert.NoError(t, err)
+	assert.Equal(t, []gofmt256.SchemaField{
+		{Name: "RecordType", From: 1, To: 1, Align: "L", Padding: " "},
+		{Name: "Name", From: 2, To: 9, Align: "L", Padding: " "},
+		{Name: "filler", From: 10, To: 13, Align: "L", Filler: true},
+		{Name: "Amount", From: 14, To: 17, Align: "R", Padding: "0"},
+		{Name: "filler", From: 18, To: 20, Align: "L", Filler: true},
+	}, schema.Fields())
+
+	// filler is ignored when parsing unless it is checked
+	var header, footer GapRecord
+	var body []GapRecord
+	data := []byte("Dname    ABCD0012   \n" + line + "\n")
+	err = gofmt256.Parse(data, &header, &body, &footer, opts...)
+	assert.NoError(t, err)
+	assert.Equal(t, record, header)
+
+	err = gofmt256.Parse(data, &header, &body, &footer, append(opts, gofmt256.WithStrictFiller(), gofmt256.WithCollectAllErrors())...)
+	assert.True(t, errors.Is(err, gofmt256.ErrPadding))
+	assert.EqualError(t, err, "2 errors occurred: "+
+		"header, line 1, offset 0: [filler] from 10 to 13: filler must be `0`; "+
+		"header, line 1, offset 0: [filler] from 18 to 20: filler must be `0`")
+}
@@ -6,4 +6,5 @@ require (
 	github.com/golang/mock v1.4.4
 	github.com/pkg/errors v0.9.1
 	github.com/stretchr/testify v1.6.1
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c
 )
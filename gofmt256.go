@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -19,25 +20,58 @@ type Builder interface {
 	Build() (string, error)
 }
 
+type BatchBuilder interface {
+	Builder
+	AddBatch(header, body, trailer interface{}) BatchBuilder
+}
+
+// Batch is a group of body records enclosed by its own header and trailer.
+type Batch struct {
+	Header  interface{}
+	Body    interface{}
+	Trailer interface{}
+}
+
 type file struct {
 	header interface{}
 	body   interface{}
 	footer interface{}
+	opts   options
 }
 
-func New(header, body, footer interface{}) Builder {
+func New(header, body, footer interface{}, opts ...Option) Builder {
 	return &file{
 		header: header,
 		body:   body,
 		footer: footer,
+		opts:   newOptions(opts),
 	}
 }
 
-func (f *file) Build() (string, error) {
-	fmt256 := ""
+// NewFile returns a builder for files made of batches. The file header is
+// followed by every batch added with AddBatch and finally the file trailer.
+func NewFile(header, trailer interface{}, opts ...Option) BatchBuilder {
+	return &file{
+		header: header,
+		body:   []Batch{},
+		footer: trailer,
+		opts:   newOptions(opts),
+	}
+}
+
+func (f *file) AddBatch(header, body, trailer interface{}) BatchBuilder {
+	batches, _ := f.body.([]Batch)
+	f.body = append(batches, Batch{
+		Header:  header,
+		Body:    body,
+		Trailer: trailer,
+	})
+	return f
+}
 
+func (f *file) Build() (string, error) {
 	headerValue := reflect.ValueOf(f.header)
-	if headerValue.Kind() != reflect.Struct {
+	if _, ok := recordMarshaler(headerValue); !ok && headerValue.Kind() != reflect.Struct {
 		return "", errors.New("header must be struct")
 	}
 
@@ -47,173 +81,429 @@ func (f *file) Build() (string, error) {
 	}
 
 	footerValue := reflect.ValueOf(f.footer)
-	if footerValue.Kind() != reflect.Struct {
+	if _, ok := recordMarshaler(footerValue); !ok && footerValue.Kind() != reflect.Struct {
 		return "", errors.New("footer must be struct")
 	}
 
-	headerLine, err := makeLine(headerValue)
+	var fmt256 strings.Builder
+	encoder := newEncoder(&fmt256, f.opts)
+	c := collector{all: f.opts.collectAllErrors}
+	// a footer that cannot be made is not a totals problem
+	footer, err := recordValue(footerValue, f.opts)
 	if err != nil {
+		return "", errors.Wrap(err, "failed to make footer")
+	}
+	if hasRecordLayout(footer.Type()) {
+		if _, err := compile(footer.Type(), f.opts); err != nil {
+			return "", errors.Wrap(err, "failed to make footer")
+		}
+	}
+	if err := encoder.TrackTotals(f.footer); err != nil {
+		return "", errors.Wrap(err, "failed to track footer totals")
+	}
+	if err := c.check(encoder.WriteHeader(f.header)); err != nil {
 		return "", err
 	}
-	fmt256 = fmt256 + headerLine
 
 	sliceLen := bodyValue.Len()
-	lines := ""
 	for i := 0; i < sliceLen; i++ {
-		line, err := makeLine(bodyValue.Index(i))
-		if err != nil {
+		record := bodyValue.Index(i).Interface()
+		if batch, ok := record.(Batch); ok {
+			if err := writeBatch(encoder, batch, &c); err != nil {
+				if isValidationError(err) {
+					return "", err
+				}
+				return "", errors.Wrapf(err, "failed to write batch %d", i+1)
+			}
+			continue
+		}
+		if err := c.check(encoder.WriteRecord(record)); err != nil {
 			return "", err
 		}
-		lines = lines + line
 	}
-	fmt256 = fmt256 + lines
 
-	footerLine, err := makeLine(footerValue)
-	if err != nil {
+	if err := c.check(encoder.WriteFooter(f.footer)); err != nil {
 		return "", err
 	}
-	fmt256 = fmt256 + footerLine
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	if err := c.err(); err != nil {
+		return "", err
+	}
+
+	return fmt256.String(), nil
+}
+
+func writeBatch(encoder *Encoder, batch Batch, c *collector) error {
+	bodyValue := reflect.ValueOf(batch.Body)
+	if bodyValue.Kind() != reflect.Slice {
+		return errors.New("batch body must be a slice")
+	}
 
-	return fmt256, nil
+	if err := encoder.TrackTotals(batch.Trailer); err != nil {
+		return errors.Wrap(err, "failed to track batch trailer totals")
+	}
+	if err := c.check(encoder.WriteBatchHeader(batch.Header)); err != nil {
+		return err
+	}
+	for i := 0; i < bodyValue.Len(); i++ {
+		if err := c.check(encoder.WriteRecord(bodyValue.Index(i).Interface())); err != nil {
+			return err
+		}
+	}
+	return c.check(encoder.WriteBatchTrailer(batch.Trailer))
 }
 
 type FieldStruct struct {
-	Name    string
-	Data    string
-	from    int
-	to      int
-	align   string
-	padding string
+	Name     string
+	Data     string
+	index    int
+	from     int
+	to       int
+	length   int
+	align    string
+	padding  string
+	seq      bool
+	agg      string
+	aggOf    string
+	where    string
+	equals   string
+	decimals int
+	rounding string
+
+	timeLayout string
+	location   *time.Location
+	era        string
+	zero       string
+
+	overflow Overflow
+	filler   bool // covers positions that no field covers
+}
+
+// lineContext carries what makeLine needs to know about a line besides the
+// record itself.
+type lineContext struct {
+	opts    options
+	section string
+	line    int
+	seq     int
+	totals  map[string]int64
 }
 
-func makeLine(input reflect.Value) (line string, err error) {
-	line = ""
+func makeLine(input reflect.Value, ctx lineContext) (string, error) {
+	var line strings.Builder
 
-	fieldStructs := make(map[string]FieldStruct)
-	for i := 0; i < input.Type().NumField(); i++ {
-		field := input.Type().Field(i)
-		if !input.FieldByName(field.Name).CanInterface() {
+	schema, err := compile(input.Type(), ctx.opts)
+	if err != nil {
+		return "", err
+	}
+	line.Grow(schema.RecordLength + 1)
+	var errs MultiError
+	for _, fs := range schema.fields {
+		if fs.filler {
+			line.WriteString(fillerData(fs, ctx.opts))
+			continue
+		}
+		switch {
+		case fs.seq:
+			fs.Data = strconv.Itoa(ctx.seq)
+		case fs.agg != "":
+			total, ok := ctx.totals[fs.Name]
+			if !ok {
+				return "", errors.Errorf("[%s] aggregate is not tracked, see Encoder.TrackTotals", fs.Name)
+			}
+			fs.Data = strconv.FormatInt(total, 10)
+		default:
+			fs.Data, err = fieldData(fs, input.Field(fs.index), ctx.opts)
+			if err == nil {
+				fs.Data, err = encodeData(fs, fs.Data, ctx.opts)
+			}
+			if err != nil {
+				if !ctx.opts.collectAllErrors {
+					return "", err
+				}
+				errs = append(errs, err)
+				continue
+			}
+		}
+		fs.Data = fit(fs, fs.Data, ctx)
+		subline, err := pad(fs, ctx.opts.unit())
+		if err != nil {
+			if !ctx.opts.collectAllErrors {
+				return "", fieldError(fs, fs.Data, err, nil)
+			}
+			errs = append(errs, fieldError(fs, fs.Data, err, nil))
 			continue
 		}
-		errLocation := "[" + field.Name + "] %s"
-		tag := field.Tag.Get(tagName)
-		subtags := strings.Split(tag, tagSep)
+		line.WriteString(subline)
+	}
+	if len(errs) > 0 {
+		return "", errs
+	}
 
-		from, to, align, padding, err := extractSubTag(subtags)
+	return line.String(), nil
+}
+
+// fieldData formats the value of a field before it is padded.
+func fieldData(fs FieldStruct, value reflect.Value, opts options) (string, error) {
+	data, ok, err := marshalField(fs, value, opts)
+	if err != nil {
+		return "", fieldError(fs, "", ErrInvalidValue, errors.Wrap(err, "unable to marshal field"))
+	}
+	if ok {
+		return data, nil
+	}
+	if fs.decimals >= 0 {
+		data, err := formatDecimal(fs, value)
 		if err != nil {
-			return "", errors.Wrapf(err, errLocation, "unable to extract subtags")
+			return "", fieldError(fs, fmt.Sprint(value.Interface()), ErrInvalidValue, errors.Wrap(err, "unable to format amount"))
 		}
-		if from < 0 || to < 0 {
-			return "", errors.New(fmt.Sprintf(errLocation, "from or to is missing from subtag or the provided value is minus"))
+		return data, nil
+	}
+	if fs.timeLayout != "" {
+		data, err := formatTime(fs, value)
+		if err != nil {
+			return "", fieldError(fs, fmt.Sprint(value.Interface()), ErrInvalidValue, errors.Wrap(err, "unable to format time"))
 		}
-		if from > to {
-			return "", errors.New(fmt.Sprintf(errLocation, "from must less than to"))
+		return data, nil
+	}
+	return fmt.Sprint(value.Interface()), nil
+}
+
+func layout(input reflect.Type, opts options) ([]FieldStruct, error) {
+	recordLength := opts.recordLength
+	if recordLength < 1 {
+		return nil, errors.New("record length must be more than 0")
+	}
+
+	var fieldStructs []FieldStruct
+	next := 1
+	for i := 0; i < input.NumField(); i++ {
+		field := input.Field(i)
+		if field.PkgPath != "" {
+			continue
 		}
-		if from > 256 || to > 256 {
-			return "", errors.New(fmt.Sprintf(errLocation, "from and to must less than 256"))
+		subtags := strings.Split(field.Tag.Get(tagName), tagSep)
+		fs, err := fieldLayout(field.Name, field.Type, subtags, recordLength, next, opts.unit())
+		if err != nil {
+			return nil, err
 		}
+		fs.index = i
+		fieldStructs = append(fieldStructs, fs)
+		next = fs.to + 1
+	}
 
-		fieldStructs[field.Name] = FieldStruct{
-			Name:    field.Name,
-			Data:    fmt.Sprint(input.Field(i).Interface()),
-			from:    from,
-			to:      to,
-			align:   align,
-			padding: padding,
-		}
+	sortedFieldStructs, err := sort(fieldStructs, recordLength, opts.filler != "")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to validate slot in %d length", recordLength)
 	}
+	return sortedFieldStructs, nil
+}
 
-	sortedFieldStructs, err := sort(fieldStructs)
+// fieldLayout reads and checks the subtags of a field of type t. A field
+// tagged with len but not from starts at next, the position after the
+// previous field. Padding must take one position in unit.
+func fieldLayout(name string, t reflect.Type, subtags []string, recordLength, next int, unit WidthUnit) (FieldStruct, error) {
+	errLocation := "[" + name + "] %s"
+	fs, err := extractSubTag(subtags)
 	if err != nil {
-		return "", errors.Wrap(err, "failed to validate slot in 256 length")
+		return FieldStruct{}, errors.Wrapf(err, errLocation, "unable to extract subtags")
 	}
-	for _, fs := range sortedFieldStructs {
-		subline, err := pad(fs)
-		if err != nil {
-			return "", errors.Wrap(err, "failed to pad data")
+	if fs.length > 0 {
+		switch {
+		case fs.from < 1 && fs.to < 1:
+			fs.from, fs.to = next, next+fs.length-1
+		case fs.from < 1:
+			fs.from = fs.to - fs.length + 1
+		case fs.to < 1:
+			fs.to = fs.from + fs.length - 1
+		case fs.to-fs.from+1 != fs.length:
+			return FieldStruct{}, errors.New(fmt.Sprintf(errLocation, fmt.Sprintf("from %d to %d is not %d long", fs.from, fs.to, fs.length)))
 		}
-		line = line + subline
+	}
+	if fs.from < 1 || fs.to < 1 {
+		return FieldStruct{}, errors.New(fmt.Sprintf(errLocation, "from or to is missing from subtag or the provided value is less than 1"))
+	}
+	if fs.align != "L" && fs.align != "R" {
+		return FieldStruct{}, errors.New(fmt.Sprintf(errLocation, fmt.Sprintf("align must be L or R, got `%s`", fs.align)))
+	}
+	if unit.Width(fs.padding) != 1 {
+		return FieldStruct{}, errors.New(fmt.Sprintf(errLocation, fmt.Sprintf("padding must take one position, got `%s`", fs.padding)))
+	}
+	if fs.where != "" && fs.agg == "" {
+		return FieldStruct{}, errors.New(fmt.Sprintf(errLocation, "where must be used with agg"))
+	}
+	if fs.rounding != "" && fs.decimals < 0 {
+		return FieldStruct{}, errors.New(fmt.Sprintf(errLocation, "rounding must be used with decimals"))
+	}
+	if fs.timeLayout != "" && t != timeType {
+		return FieldStruct{}, errors.New(fmt.Sprintf(errLocation, "layout must be used with time.Time"))
+	}
+	if fs.timeLayout == "" && (fs.location != nil || fs.era != "" || fs.zero != "") {
+		return FieldStruct{}, errors.New(fmt.Sprintf(errLocation, "tz, era and zero must be used with layout"))
+	}
+	if fs.agg != "" && fs.seq {
+		return FieldStruct{}, errors.New(fmt.Sprintf(errLocation, "agg and seq cannot be used together"))
+	}
+	if fs.from > fs.to {
+		return FieldStruct{}, errors.New(fmt.Sprintf(errLocation, fmt.Sprintf("from must less than to, got from %d to %d", fs.from, fs.to)))
+	}
+	if fs.from > recordLength || fs.to > recordLength {
+		return FieldStruct{}, errors.New(fmt.Sprintf(errLocation, fmt.Sprintf("from and to must less than %d, got from %d to %d", recordLength, fs.from, fs.to)))
 	}
 
-	line = line + "\n"
-	return line, nil
+	fs.Name = name
+	return fs, nil
 }
 
-func pad(fs FieldStruct) (string, error) {
+func pad(fs FieldStruct, unit WidthUnit) (string, error) {
 	padData := fs.Data
 	length := (fs.to - fs.from) + 1
-	if len(fs.Data) > length {
-		return "", errors.New("data is longer than length")
+	width := unit.Width(fs.Data)
+	if width > length {
+		return "", ErrTooLong
 	}
-	toPad := length - len(fs.Data)
-	for i := 0; i < toPad; i++ {
-		if fs.align == "L" {
-			padData = padData + fs.padding
-		}
-		if fs.align == "R" {
-			padData = fs.padding + padData
-		}
+	padding := strings.Repeat(fs.padding, length-width)
+	if fs.align == "L" {
+		padData = padData + padding
+	}
+	if fs.align == "R" {
+		padData = padding + padData
 	}
 	return padData, nil
 }
 
-func sort(mapFs map[string]FieldStruct) ([]FieldStruct, error) {
-	slots := [256]string{}
-	var sortedFieldStructs []FieldStruct
-	for _, fs := range mapFs {
-		for i := fs.from; i <= fs.to; i++ {
-			if slots[i-1] != "" {
-				return nil, errors.New("slot conflict between fields")
+// sort returns fields in the order of their positions. Positions that no
+// field covers fail, unless filled, when they are returned as filler ranges.
+// Errors give the positions of the fields, which may have been computed from
+// their lengths.
+func sort(fields []FieldStruct, recordLength int, filled bool) ([]FieldStruct, error) {
+	slots := make([]int, recordLength)
+	for i := range slots {
+		slots[i] = -1
+	}
+	for i, fs := range fields {
+		for p := fs.from; p <= fs.to; p++ {
+			if j := slots[p-1]; j >= 0 {
+				return nil, errors.Errorf("slot conflict between fields, [%s] from %d to %d overlaps [%s] from %d to %d",
+					fs.Name, fs.from, fs.to, fields[j].Name, fields[j].from, fields[j].to)
 			}
-			slots[i-1] = fs.Name
+			slots[p-1] = i
 		}
 	}
-	isAllocAll := true
-	for i := 1; i < len(slots); i++ {
-		if slots[i] == "" {
-			isAllocAll = false
+
+	var sortedFieldStructs []FieldStruct
+	for from := 1; from <= recordLength; {
+		if i := slots[from-1]; i >= 0 {
+			sortedFieldStructs = append(sortedFieldStructs, fields[i])
+			from = fields[i].to + 1
+			continue
 		}
-		if slots[i] != slots[i-1] {
-			sortedFieldStructs = append(sortedFieldStructs, mapFs[slots[i-1]])
+		to := from
+		for to < recordLength && slots[to] < 0 {
+			to++
 		}
+		if !filled {
+			return nil, errors.Errorf("from to not fill %d bytes, %d to %d are not covered", recordLength, from, to)
+		}
+		sortedFieldStructs = append(sortedFieldStructs, FieldStruct{
+			Name:   fillerName,
+			index:  -1,
+			from:   from,
+			to:     to,
+			align:  "L",
+			filler: true,
+		})
+		from = to + 1
 	}
-	if !isAllocAll {
-		return nil, errors.New("from to not fill 256 bytes")
-	}
-	sortedFieldStructs = append(sortedFieldStructs, mapFs[slots[len(slots)-1]])
-
 	return sortedFieldStructs, nil
 }
 
-func extractSubTag(subtags []string) (from int, to int, align string, padding string, err error) {
-	from = -1
-	to = -1
-	align = "L"
-	padding = " "
+func extractSubTag(subtags []string) (fs FieldStruct, err error) {
+	fs = FieldStruct{
+		from:     -1,
+		to:       -1,
+		align:    "L",
+		padding:  " ",
+		decimals: -1,
+	}
 	for _, subtag := range subtags {
+		if subtag == "seq" {
+			fs.seq = true
+			continue
+		}
 		splitedSubTag := strings.Split(subtag, subTagAssign)
 		if len(splitedSubTag) != 2 {
-			return -1, -1, "", "", errors.New("malformat for value within a gofmt256 tag")
+			return FieldStruct{}, errors.New("malformat for value within a gofmt256 tag")
 		}
 		if splitedSubTag[1] == "" {
-			return -1, -1, "", "", errors.New("given sub tag doesn't has an right hand value")
+			return FieldStruct{}, errors.New("given sub tag doesn't has an right hand value")
 		}
 		switch splitedSubTag[0] {
+		case "agg":
+			fs.agg, fs.aggOf, err = extractAggregate(splitedSubTag[1])
+			if err != nil {
+				return FieldStruct{}, err
+			}
+		case "decimals":
+			fs.decimals, err = strconv.Atoi(splitedSubTag[1])
+			if err != nil || fs.decimals < 0 {
+				return FieldStruct{}, errors.New("`decimals` must be a number not less than 0")
+			}
+		case "rounding":
+			fs.rounding, err = extractRounding(splitedSubTag[1])
+			if err != nil {
+				return FieldStruct{}, err
+			}
+		case "overflow":
+			fs.overflow, err = extractOverflow(splitedSubTag[1])
+			if err != nil {
+				return FieldStruct{}, err
+			}
+		case "layout":
+			fs.timeLayout = splitedSubTag[1]
+		case "tz":
+			fs.location, err = time.LoadLocation(splitedSubTag[1])
+			if err != nil {
+				return FieldStruct{}, errors.Wrap(err, "unable to load `tz`")
+			}
+		case "era":
+			fs.era, err = extractEra(splitedSubTag[1])
+			if err != nil {
+				return FieldStruct{}, err
+			}
+		case "zero":
+			fs.zero, err = extractZero(splitedSubTag[1])
+			if err != nil {
+				return FieldStruct{}, err
+			}
+		case "where":
+			condition := strings.SplitN(splitedSubTag[1], ":", 2)
+			if len(condition) != 2 || condition[0] == "" {
+				return FieldStruct{}, errors.New("`where` must be in the form of Field:Value")
+			}
+			fs.where, fs.equals = condition[0], condition[1]
 		case "from":
-			from, err = strconv.Atoi(splitedSubTag[1])
+			fs.from, err = strconv.Atoi(splitedSubTag[1])
 			if err != nil {
-				return -1, -1, "", "", errors.Wrap(err, "unable to convert `from` to `int`")
+				return FieldStruct{}, errors.Wrap(err, "unable to convert `from` to `int`")
 			}
 		case "to":
-			to, err = strconv.Atoi(splitedSubTag[1])
+			fs.to, err = strconv.Atoi(splitedSubTag[1])
 			if err != nil {
-				return -1, -1, "", "", errors.Wrap(err, "unable to covert `to` to int")
+				return FieldStruct{}, errors.Wrap(err, "unable to covert `to` to int")
+			}
+		case "len", "width":
+			fs.length, err = strconv.Atoi(splitedSubTag[1])
+			if err != nil || fs.length < 1 {
+				return FieldStruct{}, errors.Errorf("`%s` must be a number more than 0", splitedSubTag[0])
 			}
 		case "align":
-			align = splitedSubTag[1]
+			fs.align = splitedSubTag[1]
 		case "padding":
-			padding = strings.ReplaceAll(splitedSubTag[1], "'", "")
+			fs.padding = strings.ReplaceAll(splitedSubTag[1], "'", "")
 		}
 	}
-	return from, to, align, padding, nil
+	return fs, nil
 }
@@ -3,6 +3,7 @@ package gofmt256_test
 import (
 	"github.com/100x-fi/gofmt256"
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 )
 
@@ -385,3 +386,179 @@ func TestBuildFrom(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildWithRecordLength(t *testing.T) {
+	header := ShortRecordHeader{RecordType: "H", BankCode: "888"}
+	body := []ShortRecordBody{{RecordType: "D", Account: "8888888888", Amount: 51500}}
+	footer := ShortRecordFooter{RecordType: "T", Count: 1}
+
+	tests := []struct {
+		name         string
+		recordLength int
+		want         string
+		wantError    bool
+	}{
+		{
+			name:         "when generate format 94 bytes successfully",
+			recordLength: 94,
+			want: "H888" + strings.Repeat(" ", 90) + "\n" +
+				"D88888888880000051500" + strings.Repeat(" ", 73) + "\n" +
+				"T000001" + strings.Repeat(" ", 87) + "\n",
+			wantError: false,
+		},
+		{
+			name:         "when layout does not fill the record length",
+			recordLength: 100,
+			want:         "",
+			wantError:    true,
+		},
+		{
+			name:         "when layout exceeds the record length",
+			recordLength: 80,
+			want:         "",
+			wantError:    true,
+		},
+		{
+			name:         "when record length is zero",
+			recordLength: 0,
+			want:         "",
+			wantError:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := gofmt256.New(header, body, footer, gofmt256.WithRecordLength(tt.recordLength))
+			got, err := builder.Build()
+			if (err != nil) != tt.wantError {
+				t.Errorf("gofmt256.Build() err %v, wantErr %v", err, tt.wantError)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestBuildWithMixedBody(t *testing.T) {
+	got, err := gofmt256.New(getSubMerchantReportHeader(), getMixedSubMerchantReportBody(), getSubMerchantReportFooter()).Build()
+	assert.NoError(t, err)
+
+	lines := strings.Split(got, "\n")
+	assert.Len(t, lines, 7)
+	assert.Equal(t, "A000003first addenda", strings.TrimRight(lines[2], " "))
+	assert.Len(t, lines[2], 256)
+
+	_, err = gofmt256.New(getSubMerchantReportHeader(), []interface{}{"not_a_struct_for_sure"}, getSubMerchantReportFooter()).Build()
+	assert.Error(t, err)
+}
+
+func TestBuildWithBatches(t *testing.T) {
+	builder := gofmt256.NewFile(getSubMerchantReportHeader(), getSubMerchantReportFooter())
+	for _, batch := range getSubMerchantReportBatches() {
+		builder = builder.AddBatch(batch.Header, batch.Body, batch.Trailer)
+	}
+	got, err := builder.Build()
+	assert.NoError(t, err)
+
+	var recordTypes []string
+	for _, line := range strings.Split(strings.TrimSuffix(got, "\n"), "\n") {
+		recordTypes = append(recordTypes, line[:1])
+	}
+	assert.Equal(t, []string{"H", "B", "D", "D", "C", "B", "D", "C", "T"}, recordTypes)
+
+	_, err = gofmt256.NewFile(getSubMerchantReportHeader(), getSubMerchantReportFooter()).
+		AddBatch(SubMerchantReportBatchHeader{RecordType: "B"}, "not_a_slice_for_sure", SubMerchantReportBatchTrailer{RecordType: "C"}).
+		Build()
+	assert.Error(t, err)
+}
+
+func TestBuildWithSequence(t *testing.T) {
+	header := SequencedRecord{RecordType: "H"}
+	body := []SequencedRecord{{RecordType: "D"}, {RecordType: "D", SequenceNo: 99}, {RecordType: "D"}}
+	footer := SequencedRecord{RecordType: "T"}
+
+	tests := []struct {
+		name string
+		opts []gofmt256.Option
+		want string
+	}{
+		{
+			name: "when sequence runs across the whole file",
+			opts: nil,
+			want: "H000001             \nD000002             \nD000003             \nD000004             \nT000005             \n",
+		},
+		{
+			name: "when sequence starts at custom value",
+			opts: []gofmt256.Option{gofmt256.WithSequenceStart(0)},
+			want: "H000000             \nD000001             \nD000002             \nD000003             \nT000004             \n",
+		},
+		{
+			name: "when sequence restarts per section",
+			opts: []gofmt256.Option{gofmt256.WithSequencePerSection()},
+			want: "H000001             \nD000001             \nD000002             \nD000003             \nT000001             \n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]gofmt256.Option{gofmt256.WithRecordLength(20)}, tt.opts...)
+			got, err := gofmt256.New(header, body, footer, opts...).Build()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestBuildWithAggregates(t *testing.T) {
+	want, err := gofmt256.New(getSubMerchantReportHeader(), getSubMerchantReportBody(), getSubMerchantReportFooter()).Build()
+	if err != nil {
+		t.Fatalf("gofmt256.Build() err %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		footer    interface{}
+		want      string
+		wantError bool
+	}{
+		{
+			name:      "when footer totals are computed from body",
+			footer:    getSubMerchantReportAggregatedFooter(),
+			want:      want,
+			wantError: false,
+		},
+		{
+			name:      "when agg is neither count nor sum",
+			footer:    InvalidAggregate{RecordType: "T"},
+			want:      "",
+			wantError: true,
+		},
+		{
+			name:      "when agg sums a field that the body does not have",
+			footer:    MisspelledSumAggregate{RecordType: "T"},
+			want:      "",
+			wantError: true,
+		},
+		{
+			name:      "when where filters on a field that the body does not have",
+			footer:    MisspelledWhereAggregate{RecordType: "T"},
+			want:      "",
+			wantError: true,
+		},
+		{
+			name:      "when where is used without agg",
+			footer:    WhereWithoutAggregate{RecordType: "T"},
+			want:      "",
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := gofmt256.New(getSubMerchantReportHeader(), getSubMerchantReportBody(), tt.footer).Build()
+			if (err != nil) != tt.wantError {
+				t.Errorf("gofmt256.Build() err %v, wantErr %v", err, tt.wantError)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
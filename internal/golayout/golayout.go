@@ -0,0 +1,261 @@
+// Package golayout reads the structs with gofmt256 tags from Go source, for
+// tools that work with layouts without compiling the code that defines them.
+package golayout
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/100x-fi/gofmt256"
+	"github.com/pkg/errors"
+)
+
+// Struct is a struct with gofmt256 tags.
+type Struct struct {
+	Name   string
+	Fields []Field
+}
+
+// Field is a field of a Struct. Type is its Go type expression and Tag the
+// value of its gofmt256 tag.
+type Field struct {
+	Name string
+	Type string
+	Tag  string
+}
+
+// Parse reads the structs with gofmt256 tags, in the order they are declared,
+// of a Go file or of the package in a directory, leaving out test files. It
+// also returns the name of the package.
+func Parse(path string) (string, []Struct, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	if !fi.IsDir() {
+		f, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return "", nil, errors.Wrap(err, "unable to parse file")
+		}
+		files = append(files, f)
+	} else {
+		names, err := filepath.Glob(filepath.Join(path, "*.go"))
+		if err != nil {
+			return "", nil, err
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if strings.HasSuffix(name, "_test.go") {
+				continue
+			}
+			f, err := parser.ParseFile(fset, name, nil, 0)
+			if err != nil {
+				return "", nil, errors.Wrap(err, "unable to parse package")
+			}
+			files = append(files, f)
+		}
+	}
+
+	var pkg string
+	var structs []Struct
+	for _, f := range files {
+		pkg = f.Name.Name
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				st, ok := ts.Type.(*ast.StructType)
+				if !ok {
+					continue
+				}
+				s, err := parseStruct(ts.Name.Name, st)
+				if err != nil {
+					return "", nil, err
+				}
+				if len(s.Fields) > 0 {
+					structs = append(structs, s)
+				}
+			}
+		}
+	}
+	return pkg, structs, nil
+}
+
+func parseStruct(name string, st *ast.StructType) (Struct, error) {
+	s := Struct{Name: name}
+	for _, field := range st.Fields.List {
+		if field.Tag == nil {
+			continue
+		}
+		tag, err := strconv.Unquote(field.Tag.Value)
+		if err != nil {
+			return Struct{}, errors.Wrapf(err, "%s: unable to read tag", name)
+		}
+		value, ok := reflect.StructTag(tag).Lookup("gofmt256")
+		if !ok {
+			continue
+		}
+		for _, ident := range field.Names {
+			// gofmt256 skips unexported fields
+			if !ast.IsExported(ident.Name) {
+				continue
+			}
+			s.Fields = append(s.Fields, Field{
+				Name: ident.Name,
+				Type: types.ExprString(field.Type),
+				Tag:  value,
+			})
+		}
+	}
+	return s, nil
+}
+
+// Layout returns the layout described by the tags of s. Fields of types other
+// than strings, numbers, bools and time.Time are read as strings. The
+// positions of fields tagged with len are computed as gofmt256 does.
+func (s Struct) Layout() (*gofmt256.Layout, error) {
+	l := &gofmt256.Layout{Name: s.Name}
+	next := 1
+	for _, f := range s.Fields {
+		lf := gofmt256.LayoutField{Name: f.Name}
+		for _, subtag := range strings.Split(f.Tag, ",") {
+			if err := setSubtag(&lf, subtag); err != nil {
+				return nil, errors.Wrapf(err, "[%s.%s]", s.Name, f.Name)
+			}
+		}
+		if lf.Len > 0 {
+			switch {
+			case lf.From == 0 && lf.To == 0:
+				lf.From = next
+				lf.To = next + lf.Len - 1
+			case lf.From == 0:
+				lf.From = lf.To - lf.Len + 1
+			case lf.To == 0:
+				lf.To = lf.From + lf.Len - 1
+			}
+		}
+		next = lf.To + 1
+
+		switch f.Type {
+		case "string":
+			if lf.Decimals != nil {
+				lf.Type = "decimal"
+			}
+		case "int", "int8", "int16", "int32", "int64":
+			lf.Type = "int"
+		case "uint", "uint8", "uint16", "uint32", "uint64":
+			lf.Type = "uint"
+		case "float32", "float64":
+			lf.Type = "float"
+		case "bool":
+			lf.Type = "bool"
+		case "time.Time":
+			lf.Type = "time"
+		default:
+			if lf.Decimals != nil {
+				lf.Type = "decimal"
+			}
+		}
+		l.Fields = append(l.Fields, lf)
+	}
+	return l, nil
+}
+
+func setSubtag(lf *gofmt256.LayoutField, subtag string) error {
+	if subtag == "seq" {
+		lf.Seq = true
+		return nil
+	}
+	kv := strings.SplitN(subtag, "=", 2)
+	if len(kv) != 2 {
+		return errors.Errorf("malformed subtag `%s`", subtag)
+	}
+
+	var err error
+	switch kv[0] {
+	case "from":
+		lf.From, err = strconv.Atoi(kv[1])
+	case "to":
+		lf.To, err = strconv.Atoi(kv[1])
+	case "len", "width":
+		lf.Len, err = strconv.Atoi(kv[1])
+	case "align":
+		lf.Align = kv[1]
+	case "padding":
+		lf.Padding = strings.ReplaceAll(kv[1], "'", "")
+	case "decimals":
+		var decimals int
+		decimals, err = strconv.Atoi(kv[1])
+		lf.Decimals = &decimals
+	case "rounding":
+		lf.Rounding = kv[1]
+	case "layout":
+		lf.Layout = kv[1]
+	case "tz":
+		lf.TZ = kv[1]
+	case "era":
+		lf.Era = kv[1]
+	case "zero":
+		lf.Zero = kv[1]
+	case "overflow":
+		lf.Overflow = kv[1]
+	case "agg":
+		lf.Agg = kv[1]
+	case "where":
+		lf.Where = kv[1]
+	default:
+		return errors.Errorf("unknown subtag `%s`", kv[0])
+	}
+	if err != nil {
+		return errors.Errorf("`%s` must be a number", kv[0])
+	}
+	return nil
+}
+
+// FileLayout returns the layout of a file made of structs: a struct named
+// ...Header is the header, one named ...Footer or ...Trailer the footer, and
+// the others are body records.
+func FileLayout(structs []Struct) (*gofmt256.FileLayout, error) {
+	fl := &gofmt256.FileLayout{}
+	for _, s := range structs {
+		l, err := s.Layout()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case strings.HasSuffix(s.Name, "Header") && fl.Header == nil:
+			fl.Header = l
+		case (strings.HasSuffix(s.Name, "Footer") || strings.HasSuffix(s.Name, "Trailer")) && fl.Footer == nil:
+			fl.Footer = l
+		default:
+			fl.Body = append(fl.Body, l)
+		}
+	}
+	if fl.Header == nil || fl.Footer == nil || len(fl.Body) == 0 {
+		return nil, errors.New("structs must include a ...Header, a ...Footer and a body struct")
+	}
+
+	// lines are at most as long as the furthest field
+	for _, l := range append([]*gofmt256.Layout{fl.Header, fl.Footer}, fl.Body...) {
+		for _, f := range l.Fields {
+			if f.To > fl.Length {
+				fl.Length = f.To
+			}
+		}
+	}
+	return fl, nil
+}
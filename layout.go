@@ -0,0 +1,411 @@
+package gofmt256
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Layout describes a record without a Go struct, so that layouts can be
+// loaded from configuration. Records of a layout are LayoutRecord values. A
+// layout must not be changed once it has been used. RecordType is the code
+// of its lines, used by FileLayout to tell body lines apart.
+type Layout struct {
+	Name       string        `json:"name,omitempty" yaml:"name,omitempty"`
+	RecordType string        `json:"record_type,omitempty" yaml:"record_type,omitempty"`
+	Fields     []LayoutField `json:"fields" yaml:"fields"`
+}
+
+// LayoutField describes a field of a Layout. Type is one of string (the
+// default), int, uint, float, bool, decimal or time. The other fields have
+// the meaning of the subtag of the same name.
+type LayoutField struct {
+	Name     string `json:"name" yaml:"name"`
+	From     int    `json:"from" yaml:"from"`
+	To       int    `json:"to" yaml:"to"`
+	Len      int    `json:"len,omitempty" yaml:"len,omitempty"`
+	Align    string `json:"align,omitempty" yaml:"align,omitempty"`
+	Padding  string `json:"padding,omitempty" yaml:"padding,omitempty"`
+	Type     string `json:"type,omitempty" yaml:"type,omitempty"`
+	Decimals *int   `json:"decimals,omitempty" yaml:"decimals,omitempty"`
+	Rounding string `json:"rounding,omitempty" yaml:"rounding,omitempty"`
+	Layout   string `json:"layout,omitempty" yaml:"layout,omitempty"`
+	TZ       string `json:"tz,omitempty" yaml:"tz,omitempty"`
+	Era      string `json:"era,omitempty" yaml:"era,omitempty"`
+	Zero     string `json:"zero,omitempty" yaml:"zero,omitempty"`
+	Overflow string `json:"overflow,omitempty" yaml:"overflow,omitempty"`
+	Seq      bool   `json:"seq,omitempty" yaml:"seq,omitempty"`
+	Agg      string `json:"agg,omitempty" yaml:"agg,omitempty"`
+	Where    string `json:"where,omitempty" yaml:"where,omitempty"`
+}
+
+// LayoutRecord is a record of a Layout. Values are keyed by field name. They
+// are built from values of the field type or of a type convertible to it,
+// including strings, and parsed into string, int64, uint64, float64, bool, a
+// string for decimal or time.Time values.
+type LayoutRecord struct {
+	Layout *Layout
+	Values map[string]interface{}
+}
+
+var (
+	layoutRecordType = reflect.TypeOf(LayoutRecord{})
+
+	layoutFieldTypes = map[string]reflect.Type{
+		"":        reflect.TypeOf(""),
+		"string":  reflect.TypeOf(""),
+		"int":     reflect.TypeOf(int64(0)),
+		"uint":    reflect.TypeOf(uint64(0)),
+		"float":   reflect.TypeOf(float64(0)),
+		"bool":    reflect.TypeOf(false),
+		"decimal": reflect.TypeOf(""),
+		"time":    timeType,
+	}
+)
+
+type layoutKey struct {
+	layout       *Layout
+	recordLength int
+	filled       bool
+	unit         WidthUnit
+}
+
+var layoutSchemas sync.Map
+
+// ParseLayout reads a Layout from JSON or YAML.
+func ParseLayout(data []byte) (*Layout, error) {
+	var l Layout
+	if err := yaml.Unmarshal(data, &l); err != nil {
+		return nil, errors.Wrap(err, "unable to read layout")
+	}
+	return &l, nil
+}
+
+// LoadLayout reads a Layout from a JSON or YAML file.
+func LoadLayout(path string) (*Layout, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read layout")
+	}
+	return ParseLayout(data)
+}
+
+// FileLayout describes every record of a file, for tools that work on files
+// without Go structs. Body lines are told apart by the RecordType of their
+// layout when there is more than one body layout. Length defaults to 256, the
+// record type position to 1, the width unit to bytes, the encoding, named
+// as for LookupEncoding, to UTF-8 and the line ending to LF with a trailing
+// newline. Positions that no field covers fail the layout unless Filler is
+// set.
+type FileLayout struct {
+	Length          int        `json:"length,omitempty" yaml:"length,omitempty"`
+	RecordTypeFrom  int        `json:"record_type_from,omitempty" yaml:"record_type_from,omitempty"`
+	RecordTypeTo    int        `json:"record_type_to,omitempty" yaml:"record_type_to,omitempty"`
+	WidthUnit       WidthUnit  `json:"width_unit,omitempty" yaml:"width_unit,omitempty"`
+	Encoding        string     `json:"encoding,omitempty" yaml:"encoding,omitempty"`
+	Unmappable      Unmappable `json:"unmappable,omitempty" yaml:"unmappable,omitempty"`
+	LineEnding      LineEnding `json:"line_ending,omitempty" yaml:"line_ending,omitempty"`
+	TrailingNewline *bool      `json:"trailing_newline,omitempty" yaml:"trailing_newline,omitempty"`
+	Filler          string     `json:"filler,omitempty" yaml:"filler,omitempty"`
+	StrictFiller    bool       `json:"strict_filler,omitempty" yaml:"strict_filler,omitempty"`
+	Header          *Layout    `json:"header" yaml:"header"`
+	Body            []*Layout  `json:"body" yaml:"body"`
+	Footer          *Layout    `json:"footer" yaml:"footer"`
+}
+
+// ParseFileLayout reads a FileLayout from JSON or YAML. A single Layout is
+// read as the layout of every line.
+func ParseFileLayout(data []byte) (*FileLayout, error) {
+	var probe struct {
+		Fields []interface{} `yaml:"fields"`
+	}
+	if err := yaml.Unmarshal(data, &probe); err != nil {
+		return nil, errors.Wrap(err, "unable to read layout")
+	}
+	if probe.Fields != nil {
+		l, err := ParseLayout(data)
+		if err != nil {
+			return nil, err
+		}
+		return &FileLayout{Header: l, Body: []*Layout{l}, Footer: l}, nil
+	}
+
+	var l FileLayout
+	if err := yaml.Unmarshal(data, &l); err != nil {
+		return nil, errors.Wrap(err, "unable to read layout")
+	}
+	if l.Header == nil || l.Footer == nil || len(l.Body) == 0 {
+		return nil, errors.New("layout must have header, body and footer")
+	}
+	if _, err := LookupEncoding(l.Encoding); err != nil {
+		return nil, err
+	}
+	switch l.LineEnding {
+	case "", LineEndingLF, LineEndingCRLF, LineEndingCR, LineEndingNone:
+	default:
+		return nil, errors.Errorf("unknown line ending `%s`", l.LineEnding)
+	}
+	if l.Filler != "" && utf8.RuneCountInString(l.Filler) != 1 {
+		return nil, errors.Errorf("filler must be a single character, got `%s`", l.Filler)
+	}
+	return &l, nil
+}
+
+// LoadFileLayout reads a FileLayout from a JSON or YAML file.
+func LoadFileLayout(path string) (*FileLayout, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read layout")
+	}
+	return ParseFileLayout(data)
+}
+
+// Options returns the options to build and parse files of l: its record
+// length, record type position, width unit, encoding, line ending, filler and
+// the record types of its body layouts. A single body layout is used for every
+// body line whatever its record type.
+func (l *FileLayout) Options() []Option {
+	var opts []Option
+	if l.Length != 0 {
+		opts = append(opts, WithRecordLength(l.Length))
+	}
+	if l.RecordTypeFrom != 0 {
+		to := l.RecordTypeTo
+		if to == 0 {
+			to = l.RecordTypeFrom
+		}
+		opts = append(opts, WithRecordTypePosition(l.RecordTypeFrom, to))
+	}
+	if l.WidthUnit != "" {
+		opts = append(opts, WithWidthUnit(l.WidthUnit))
+	}
+	if enc, err := LookupEncoding(l.Encoding); enc != nil && err == nil {
+		opts = append(opts, WithEncoding(enc))
+	}
+	if l.Unmappable != "" {
+		opts = append(opts, WithUnmappable(l.Unmappable))
+	}
+	if l.LineEnding != "" {
+		opts = append(opts, WithLineEnding(l.LineEnding))
+	}
+	if l.TrailingNewline != nil {
+		opts = append(opts, WithTrailingNewline(*l.TrailingNewline))
+	}
+	if l.Filler != "" {
+		opts = append(opts, WithFiller(l.Filler))
+	}
+	if l.StrictFiller {
+		opts = append(opts, WithStrictFiller())
+	}
+	for _, body := range l.Body {
+		if body.RecordType != "" {
+			opts = append(opts, WithRecordType(body.RecordType, body))
+		}
+	}
+	if len(l.Body) == 1 {
+		body := l.Body[0]
+		opts = append(opts, func(o *options) {
+			o.bodyLayout = body
+		})
+	}
+	return opts
+}
+
+// RecordLayout returns the section and layout of a line of a file of lines
+// lines, given the record type of the line.
+func (l *FileLayout) RecordLayout(line, lines int, recordType string) (string, *Layout, error) {
+	switch {
+	case line == 1:
+		return SectionHeader, l.Header, nil
+	case line == lines:
+		return SectionFooter, l.Footer, nil
+	case len(l.Body) == 1:
+		return SectionBody, l.Body[0], nil
+	}
+	for _, body := range l.Body {
+		if body.RecordType == recordType {
+			return SectionBody, body, nil
+		}
+	}
+	return SectionBody, nil, errors.Errorf("no layout is defined for record type `%s`", recordType)
+}
+
+// compile builds a struct type for l, whose schema is named after the fields
+// of l, so that records of l are built and parsed like tagged structs.
+func (l *Layout) compile(opts options) (*Schema, error) {
+	recordLength := opts.recordLength
+	if l == nil {
+		return nil, errors.New("layout is nil")
+	}
+	if recordLength < 1 {
+		return nil, errors.New("record length must be more than 0")
+	}
+	if err := checkFiller(opts); err != nil {
+		return nil, err
+	}
+	key := layoutKey{layout: l, recordLength: recordLength, filled: opts.filler != "", unit: opts.unit()}
+	if s, ok := layoutSchemas.Load(key); ok {
+		return s.(*Schema), nil
+	}
+
+	structFields := make([]reflect.StructField, len(l.Fields))
+	fieldStructs := make([]FieldStruct, 0, len(l.Fields))
+	names := make(map[string]bool, len(l.Fields))
+	next := 1
+	for i, f := range l.Fields {
+		if f.Name == "" {
+			return nil, errors.Errorf("name of field %d is missing", i+1)
+		}
+		if names[f.Name] {
+			return nil, errors.Errorf("[%s] field is defined more than once", f.Name)
+		}
+		t, ok := layoutFieldTypes[f.Type]
+		if !ok {
+			return nil, errors.Errorf("[%s] type must be one of string, int, uint, float, bool, decimal or time, got `%s`", f.Name, f.Type)
+		}
+		if f.Type == "decimal" && f.Decimals == nil {
+			return nil, errors.Errorf("[%s] decimal must be used with decimals", f.Name)
+		}
+		if f.Type == "time" && f.Layout == "" {
+			return nil, errors.Errorf("[%s] time must be used with layout", f.Name)
+		}
+
+		subtags := f.subtags()
+		fs, err := fieldLayout(f.Name, t, subtags, recordLength, next, opts.unit())
+		if err != nil {
+			return nil, err
+		}
+		fs.index = i
+		fieldStructs = append(fieldStructs, fs)
+		names[f.Name] = true
+		next = fs.to + 1
+
+		structFields[i] = reflect.StructField{
+			Name: "F" + strconv.Itoa(i),
+			Type: t,
+			Tag:  reflect.StructTag(fmt.Sprintf(`%s:%q name:%q`, tagName, strings.Join(subtags, tagSep), f.Name)),
+		}
+	}
+
+	fields, err := sort(fieldStructs, recordLength, key.filled)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to validate slot in %d length", recordLength)
+	}
+	s := storeSchema(schemaKey{t: reflect.StructOf(structFields), recordLength: recordLength, filled: key.filled, unit: key.unit}, fields)
+	actual, _ := layoutSchemas.LoadOrStore(key, s)
+	return actual.(*Schema), nil
+}
+
+func (f LayoutField) subtags() []string {
+	var subtags []string
+	add := func(key, value string) {
+		if value != "" {
+			subtags = append(subtags, key+subTagAssign+value)
+		}
+	}
+	position := func(key string, value int) {
+		if value != 0 {
+			add(key, strconv.Itoa(value))
+		}
+	}
+	position("from", f.From)
+	position("to", f.To)
+	position("len", f.Len)
+	add("align", f.Align)
+	add("padding", f.Padding)
+	if f.Decimals != nil {
+		add("decimals", strconv.Itoa(*f.Decimals))
+	}
+	add("rounding", f.Rounding)
+	add("layout", f.Layout)
+	add("tz", f.TZ)
+	add("era", f.Era)
+	add("zero", f.Zero)
+	add("overflow", f.Overflow)
+	add("agg", f.Agg)
+	add("where", f.Where)
+	if f.Seq {
+		subtags = append(subtags, "seq")
+	}
+	return subtags
+}
+
+// recordValue returns record itself, or for a LayoutRecord a value of the
+// struct type compiled from its layout.
+func recordValue(record reflect.Value, opts options) (reflect.Value, error) {
+	if !record.IsValid() || record.Type() != layoutRecordType {
+		return record, nil
+	}
+	r := record.Interface().(LayoutRecord)
+	schema, err := r.Layout.compile(opts)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	value := reflect.New(schema.Type).Elem()
+	for name, v := range r.Values {
+		fs, ok := schema.byName[name]
+		if !ok {
+			return reflect.Value{}, errors.Errorf("[%s] field is not in the layout", name)
+		}
+		if err := setLayoutValue(value.Field(fs.index), v); err != nil {
+			return reflect.Value{}, fieldError(fs, fmt.Sprint(v), ErrInvalidValue, err)
+		}
+	}
+	return value, nil
+}
+
+func setLayoutValue(field reflect.Value, v interface{}) error {
+	if v == nil {
+		return nil
+	}
+	value := reflect.ValueOf(v)
+	switch {
+	case value.Type() == field.Type():
+		field.Set(value)
+	case field.Kind() == reflect.String:
+		field.SetString(fmt.Sprint(v))
+	case value.Kind() == reflect.String:
+		if field.Type() == timeType {
+			t, err := time.Parse(time.RFC3339, value.String())
+			if err != nil {
+				return errors.Wrapf(err, "unable to convert `%s` to `%s`", value.String(), field.Type())
+			}
+			field.Set(reflect.ValueOf(t))
+			return nil
+		}
+		return setField(field, strings.TrimSpace(value.String()))
+	case isNumber(value.Kind()) && isNumber(field.Kind()):
+		if (value.Kind() == reflect.Float32 || value.Kind() == reflect.Float64) &&
+			field.Kind() != reflect.Float32 && field.Kind() != reflect.Float64 &&
+			value.Float() != math.Trunc(value.Float()) {
+			return errors.Errorf("`%v` is not a whole number", v)
+		}
+		field.Set(value.Convert(field.Type()))
+	default:
+		return errors.Errorf("unable to convert `%T` to `%s`", v, field.Type())
+	}
+	return nil
+}
+
+func isNumber(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Float64
+}
+
+// layoutValues returns the values of a record of l decoded into value.
+func layoutValues(l *Layout, value reflect.Value) map[string]interface{} {
+	values := make(map[string]interface{}, len(l.Fields))
+	for i, f := range l.Fields {
+		values[f.Name] = value.Field(i).Interface()
+	}
+	return values
+}
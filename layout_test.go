@@ -0,0 +1,124 @@
+package gofmt256_test
+
+import (
+	"testing"
+
+	"github.com/100x-fi/gofmt256"
+	"github.com/stretchr/testify/assert"
+)
+
+const detailLayout = `
+name: detail
+fields:
+  - {name: record_type, from: 1, to: 1}
+  - {name: account, from: 2, to: 11}
+  - {name: amount, from: 12, to: 20, align: R, padding: "0", type: decimal, decimals: 2}
+`
+
+const trailerLayout = `{
+  "name": "trailer",
+  "fields": [
+    {"name": "record_type", "from": 1, "to": 1},
+    {"name": "count", "from": 2, "to": 7, "align": "R", "padding": "0", "type": "int", "agg": "count"},
+    {"name": "total", "from": 8, "to": 20, "align": "R", "padding": "0", "type": "int", "agg": "sum(amount)"}
+  ]
+}`
+
+func TestLayout(t *testing.T) {
+	detail, err := gofmt256.ParseLayout([]byte(detailLayout))
+	assert.NoError(t, err)
+	trailer, err := gofmt256.ParseLayout([]byte(trailerLayout))
+	assert.NoError(t, err)
+	_, err = gofmt256.Compile(detail, gofmt256.WithRecordLength(20))
+	assert.NoError(t, err)
+
+	header := gofmt256.LayoutRecord{Layout: detail, Values: map[string]interface{}{"record_type": "H"}}
+	body := []gofmt256.LayoutRecord{
+		{Layout: detail, Values: map[string]interface{}{"record_type": "D", "account": "1234567890", "amount": 515.5}},
+		{Layout: detail, Values: map[string]interface{}{"record_type": "D", "account": 987, "amount": "20"}},
+	}
+	footer := gofmt256.LayoutRecord{Layout: trailer, Values: map[string]interface{}{"record_type": "T"}}
+
+	got, err := gofmt256.New(header, body, footer, gofmt256.WithRecordLength(20)).Build()
+	assert.NoError(t, err)
+	want := "H          000000000\n" +
+		"D1234567890000051550\n" +
+		"D987       000002000\n" +
+		"T0000020000000053550\n"
+	assert.Equal(t, want, got)
+
+	parsedHeader := gofmt256.LayoutRecord{Layout: detail}
+	parsedFooter := gofmt256.LayoutRecord{Layout: trailer}
+	var parsedBody []gofmt256.LayoutRecord
+	err = gofmt256.Parse([]byte(got), &parsedHeader, &parsedBody, &parsedFooter,
+		gofmt256.WithRecordLength(20), gofmt256.WithRecordType("D", detail))
+	assert.NoError(t, err)
+	assert.Equal(t, "H", parsedHeader.Values["record_type"])
+	if assert.Len(t, parsedBody, 2) {
+		assert.Equal(t, map[string]interface{}{"record_type": "D", "account": "1234567890", "amount": "515.50"}, parsedBody[0].Values)
+	}
+	assert.Equal(t, map[string]interface{}{"record_type": "T", "count": int64(2), "total": int64(53550)}, parsedFooter.Values)
+}
+
+func TestFileLayout(t *testing.T) {
+	l, err := gofmt256.ParseFileLayout([]byte(detailLayout))
+	assert.NoError(t, err)
+	assert.Len(t, l.Body, 1)
+	l.Length = 20
+
+	var header, footer gofmt256.LayoutRecord
+	header.Layout, footer.Layout = l.Header, l.Footer
+	var body []gofmt256.LayoutRecord
+	err = gofmt256.Parse([]byte("H          000000000\nX1234567890000051550\nT          000000000\n"), &header, &body, &footer, l.Options()...)
+	assert.NoError(t, err)
+	if assert.Len(t, body, 1) {
+		assert.Equal(t, "X", body[0].Values["record_type"])
+	}
+
+	_, err = gofmt256.ParseFileLayout([]byte(`{"header": {"fields": []}}`))
+	assert.Error(t, err)
+
+	gaps := "length: 20\n" +
+		"header: {fields: [{name: a, from: 1, to: 10}]}\n" +
+		"body: [{fields: [{name: a, from: 1, to: 10}]}]\n" +
+		"footer: {fields: [{name: a, from: 11, to: 20}]}\n"
+	l, err = gofmt256.ParseFileLayout([]byte("filler: \" \"\n" + gaps))
+	assert.NoError(t, err)
+	record := gofmt256.LayoutRecord{Layout: l.Header, Values: map[string]interface{}{"a": "x"}}
+	got, err := gofmt256.New(record, []gofmt256.LayoutRecord{}, gofmt256.LayoutRecord{Layout: l.Footer}, l.Options()...).Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "x                   \n                    \n", got)
+	_, err = gofmt256.ParseFileLayout([]byte("filler: \"00\"\n" + gaps))
+	assert.Error(t, err)
+}
+
+func TestLayoutErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		layout string
+	}{
+		{name: "unknown type", layout: `fields: [{name: a, from: 1, to: 20, type: date}]`},
+		{name: "missing name", layout: `fields: [{from: 1, to: 20}]`},
+		{name: "duplicate name", layout: `fields: [{name: a, from: 1, to: 10}, {name: a, from: 11, to: 20}]`},
+		{name: "decimal without decimals", layout: `fields: [{name: a, from: 1, to: 20, type: decimal}]`},
+		{name: "time without layout", layout: `fields: [{name: a, from: 1, to: 20, type: time}]`},
+		{name: "gap", layout: `fields: [{name: a, from: 1, to: 10}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := gofmt256.ParseLayout([]byte(tt.layout))
+			assert.NoError(t, err)
+			_, err = gofmt256.Compile(l, gofmt256.WithRecordLength(20))
+			assert.Error(t, err)
+		})
+	}
+
+	l, err := gofmt256.ParseLayout([]byte(detailLayout))
+	assert.NoError(t, err)
+	record := gofmt256.LayoutRecord{Layout: l, Values: map[string]interface{}{"amount": "abc"}}
+	_, err = gofmt256.New(record, []gofmt256.LayoutRecord{}, record, gofmt256.WithRecordLength(20)).Build()
+	assert.Error(t, err)
+	record.Values = map[string]interface{}{"unknown": "x"}
+	_, err = gofmt256.New(record, []gofmt256.LayoutRecord{}, record, gofmt256.WithRecordLength(20)).Build()
+	assert.Error(t, err)
+}
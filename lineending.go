@@ -0,0 +1,95 @@
+package gofmt256
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+)
+
+// LineEnding ends every line of a file.
+type LineEnding string
+
+const (
+	// LineEndingLF ends lines with "\n". It is the default.
+	LineEndingLF LineEnding = "lf"
+	// LineEndingCRLF ends lines with "\r\n".
+	LineEndingCRLF LineEnding = "crlf"
+	// LineEndingCR ends lines with "\r".
+	LineEndingCR LineEnding = "cr"
+	// LineEndingNone writes lines as fixed-length blocks one after the
+	// other, as mainframes do. Lines are then read by their length in bytes,
+	// which positions in runes or cells do not give in UTF-8.
+	LineEndingNone LineEnding = "none"
+)
+
+// WithLineEnding sets the line ending of files. It defaults to LineEndingLF.
+func WithLineEnding(ending LineEnding) Option {
+	return func(o *options) {
+		o.lineEnding = ending
+	}
+}
+
+// WithTrailingNewline sets whether the last line of a file has a line
+// ending. It defaults to true, with which files are read with or without it.
+// When false, the footer is written without a line ending and files that end
+// with one are rejected.
+func WithTrailingNewline(trailing bool) Option {
+	return func(o *options) {
+		o.trailingNewline = trailing
+	}
+}
+
+// Chars returns the characters that end lines with l, which are none for
+// LineEndingNone.
+func (l LineEnding) Chars() string {
+	switch l {
+	case LineEndingCRLF:
+		return "\r\n"
+	case LineEndingCR:
+		return "\r"
+	case LineEndingNone:
+		return ""
+	}
+	return "\n"
+}
+
+// ending returns the line ending of opts in their encoding.
+func (o options) ending() string {
+	ending := o.lineEnding.Chars()
+	if o.encoding == nil {
+		return ending
+	}
+	data, _ := o.encoding.Encode(ending)
+	return string(data)
+}
+
+// blockSize returns the size in bytes of lines without a line ending, or an
+// error when their width does not give it.
+func (o options) blockSize() (int, error) {
+	if o.unit() == WidthCells || (o.encoding == nil && !o.widthUnit.isBytes()) {
+		return 0, errors.Errorf("lines without a line ending must be measured in bytes, not %s", o.widthUnit)
+	}
+	return o.recordLength, nil
+}
+
+// countLines returns the number of lines of data.
+func countLines(data []byte, opts options) (int, error) {
+	ending := opts.ending()
+	if ending == "" {
+		size, err := opts.blockSize()
+		if err != nil {
+			return 0, err
+		}
+		return (len(data) + size - 1) / size, nil
+	}
+
+	trailing := bytes.HasSuffix(data, []byte(ending))
+	if trailing && !opts.trailingNewline {
+		return 0, errors.New("data must not end with a line ending")
+	}
+	n := bytes.Count(data, []byte(ending))
+	if len(data) > 0 && !trailing {
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,84 @@
+package gofmt256_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/100x-fi/gofmt256"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineEnding(t *testing.T) {
+	record := OverflowRecord{RecordType: "D", Name: "name", Reference: "123", Note: "ok"}
+	line := "Dname    123   ok   "
+
+	tests := []struct {
+		name     string
+		ending   gofmt256.LineEnding
+		trailing bool
+		want     string
+	}{
+		{name: "lf", ending: gofmt256.LineEndingLF, trailing: true, want: line + "\n" + line + "\n" + line + "\n"},
+		{name: "crlf", ending: gofmt256.LineEndingCRLF, trailing: true, want: line + "\r\n" + line + "\r\n" + line + "\r\n"},
+		{name: "cr", ending: gofmt256.LineEndingCR, trailing: true, want: line + "\r" + line + "\r" + line + "\r"},
+		{name: "crlf without trailing newline", ending: gofmt256.LineEndingCRLF, want: line + "\r\n" + line + "\r\n" + line},
+		{name: "none", ending: gofmt256.LineEndingNone, trailing: true, want: line + line + line},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := []gofmt256.Option{
+				gofmt256.WithRecordLength(20),
+				gofmt256.WithLineEnding(tt.ending),
+				gofmt256.WithTrailingNewline(tt.trailing),
+			}
+			got, err := gofmt256.New(record, []OverflowRecord{record}, record, opts...).Build()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			var header, footer OverflowRecord
+			var body []OverflowRecord
+			err = gofmt256.Parse([]byte(got), &header, &body, &footer, opts...)
+			assert.NoError(t, err)
+			assert.Equal(t, []OverflowRecord{record}, body)
+			assert.Equal(t, record, footer)
+		})
+	}
+}
+
+func TestLineEndingErrors(t *testing.T) {
+	record := OverflowRecord{RecordType: "D", Name: "name"}
+	line := "Dname               "
+	var header, footer OverflowRecord
+	var body []OverflowRecord
+
+	// a trailing newline is optional unless turned off
+	opts := []gofmt256.Option{gofmt256.WithRecordLength(20), gofmt256.WithLineEnding(gofmt256.LineEndingCRLF)}
+	err := gofmt256.Parse([]byte(line+"\r\n"+line), &header, &body, &footer, opts...)
+	assert.NoError(t, err)
+	err = gofmt256.Parse([]byte(line+"\r\n"+line+"\r\n"), &header, &body, &footer,
+		append(opts, gofmt256.WithTrailingNewline(false))...)
+	assert.EqualError(t, err, "data must not end with a line ending")
+
+	err = gofmt256.Parse([]byte(line+"\r\n"+line+"\n"+line+"\r\n"), &header, &body, &footer, opts...)
+	assert.True(t, errors.Is(err, gofmt256.ErrLineLength))
+
+	decoder := gofmt256.NewDecoder(bytes.NewReader([]byte(line+"\r\n")), append(opts, gofmt256.WithTrailingNewline(false))...)
+	assert.False(t, decoder.Next())
+	assert.EqualError(t, decoder.Err(), "line 1, offset 0: last line must not end with a line ending")
+
+	// lines without a line ending are read by their length in bytes
+	opts = []gofmt256.Option{
+		gofmt256.WithRecordLength(20),
+		gofmt256.WithLineEnding(gofmt256.LineEndingNone),
+		gofmt256.WithWidthUnit(gofmt256.WidthRunes),
+	}
+	_, err = gofmt256.New(record, []OverflowRecord{}, record, opts...).Build()
+	assert.EqualError(t, err, "failed to write header at line 1: lines without a line ending must be measured in bytes, not runes")
+	err = gofmt256.Parse([]byte(line+line), &header, &body, &footer, opts...)
+	assert.EqualError(t, err, "lines without a line ending must be measured in bytes, not runes")
+
+	opts = []gofmt256.Option{gofmt256.WithRecordLength(20), gofmt256.WithLineEnding(gofmt256.LineEndingNone)}
+	err = gofmt256.Parse([]byte(line+line[:10]), &header, &body, &footer, opts...)
+	assert.EqualError(t, err, "footer, line 2, offset 20: line must be 20 bytes long")
+}
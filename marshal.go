@@ -0,0 +1,172 @@
+package gofmt256
+
+import (
+	"bytes"
+	"reflect"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Field256Marshaler is implemented by types that render their own value of a
+// field. width is the number of positions allocated to the field; a shorter
+// result is padded according to the tag.
+type Field256Marshaler interface {
+	MarshalField256(width int) (string, error)
+}
+
+// Field256Unmarshaler is implemented by types that parse their own value of a
+// field from its data with the padding stripped.
+type Field256Unmarshaler interface {
+	UnmarshalField256(data string) error
+}
+
+// FieldFormatter renders and parses the values of a type that does not
+// implement Field256Marshaler and Field256Unmarshaler, such as a type from
+// another package. Unmarshal returns a value of the registered type.
+type FieldFormatter struct {
+	Marshal   func(v interface{}, width int) (string, error)
+	Unmarshal func(data string) (interface{}, error)
+}
+
+var (
+	fieldMarshalerType   = reflect.TypeOf((*Field256Marshaler)(nil)).Elem()
+	fieldUnmarshalerType = reflect.TypeOf((*Field256Unmarshaler)(nil)).Elem()
+
+	formattersMu sync.RWMutex
+	formatters   = make(map[reflect.Type]FieldFormatter)
+)
+
+// RegisterFieldFormatter registers f for every field of the type of v. A
+// formatter given to a builder or parser with WithFieldFormatter takes
+// precedence.
+func RegisterFieldFormatter(v interface{}, f FieldFormatter) {
+	formattersMu.Lock()
+	defer formattersMu.Unlock()
+	formatters[reflect.TypeOf(v)] = f
+}
+
+func lookupFieldFormatter(t reflect.Type, opts options) (FieldFormatter, bool) {
+	if f, ok := opts.formatters[t]; ok {
+		return f, true
+	}
+	formattersMu.RLock()
+	defer formattersMu.RUnlock()
+	f, ok := formatters[t]
+	return f, ok
+}
+
+// marshalField renders value with a registered formatter or its
+// Field256Marshaler implementation. ok is false when neither applies.
+func marshalField(fs FieldStruct, value reflect.Value, opts options) (data string, ok bool, err error) {
+	width := fs.to - fs.from + 1
+	if f, found := lookupFieldFormatter(value.Type(), opts); found && f.Marshal != nil {
+		data, err = f.Marshal(value.Interface(), width)
+		return data, true, err
+	}
+	if value.Type().Implements(fieldMarshalerType) {
+		if value.Kind() == reflect.Ptr && value.IsNil() {
+			return "", true, nil
+		}
+		data, err = value.Interface().(Field256Marshaler).MarshalField256(width)
+		return data, true, err
+	}
+	if value.CanAddr() && value.Addr().Type().Implements(fieldMarshalerType) {
+		data, err = value.Addr().Interface().(Field256Marshaler).MarshalField256(width)
+		return data, true, err
+	}
+	return "", false, nil
+}
+
+// unmarshalField parses data into field with a registered formatter or its
+// Field256Unmarshaler implementation. ok is false when neither applies.
+func unmarshalField(field reflect.Value, data string, opts options) (ok bool, err error) {
+	if f, found := lookupFieldFormatter(field.Type(), opts); found && f.Unmarshal != nil {
+		v, err := f.Unmarshal(data)
+		if err != nil {
+			return true, err
+		}
+		value := reflect.ValueOf(v)
+		if !value.IsValid() || value.Type() != field.Type() {
+			return true, errors.Errorf("formatter of `%s` returned `%T`", field.Type(), v)
+		}
+		field.Set(value)
+		return true, nil
+	}
+	if field.Kind() == reflect.Ptr && field.Type().Implements(fieldUnmarshalerType) {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		return true, field.Interface().(Field256Unmarshaler).UnmarshalField256(data)
+	}
+	if field.CanAddr() && field.Addr().Type().Implements(fieldUnmarshalerType) {
+		return true, field.Addr().Interface().(Field256Unmarshaler).UnmarshalField256(data)
+	}
+	return false, nil
+}
+
+// Record256Marshaler is implemented by types that render a whole line
+// themselves, for layouts that cannot be described by tags alone. The result
+// must be exactly the record length and must not include the line ending.
+type Record256Marshaler interface {
+	MarshalRecord256() ([]byte, error)
+}
+
+// Record256Unmarshaler is implemented by types that parse a whole line
+// themselves. data does not include the line ending.
+type Record256Unmarshaler interface {
+	UnmarshalRecord256(data []byte) error
+}
+
+var (
+	recordMarshalerType   = reflect.TypeOf((*Record256Marshaler)(nil)).Elem()
+	recordUnmarshalerType = reflect.TypeOf((*Record256Unmarshaler)(nil)).Elem()
+)
+
+// recordMarshaler returns the Record256Marshaler implementation of value or
+// of a pointer to it.
+func recordMarshaler(value reflect.Value) (Record256Marshaler, bool) {
+	if !value.IsValid() {
+		return nil, false
+	}
+	if value.Type().Implements(recordMarshalerType) {
+		if value.Kind() == reflect.Ptr && value.IsNil() {
+			return nil, false
+		}
+		return value.Interface().(Record256Marshaler), true
+	}
+	if reflect.PtrTo(value.Type()).Implements(recordMarshalerType) {
+		ptr := reflect.New(value.Type())
+		ptr.Elem().Set(value)
+		return ptr.Interface().(Record256Marshaler), true
+	}
+	return nil, false
+}
+
+func marshalRecord(m Record256Marshaler, opts options) (string, error) {
+	data, err := m.MarshalRecord256()
+	if err != nil {
+		return "", lineError("", ErrInvalidValue, errors.Wrap(err, "unable to marshal record"))
+	}
+	if width := opts.unit().Width(string(data)); width != opts.recordLength {
+		return "", lineError(string(data), ErrLineLength, errors.Errorf("marshaled record must be %d %s long, got %d", opts.recordLength, opts.widthUnit, width))
+	}
+	if bytes.ContainsAny(data, "\r\n") {
+		return "", lineError(string(data), ErrInvalidValue, errors.New("marshaled record must not contain a line ending"))
+	}
+	return string(data), nil
+}
+
+// hasRecordLayout reports whether lines of type t are described by tags
+// rather than by Record256Marshaler or Record256Unmarshaler.
+func hasRecordLayout(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for _, i := range []reflect.Type{recordMarshalerType, recordUnmarshalerType} {
+		if t.Implements(i) || reflect.PtrTo(t).Implements(i) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,120 @@
+package gofmt256_test
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/100x-fi/gofmt256"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldMarshaler(t *testing.T) {
+	gofmt256.RegisterFieldFormatter(CurrencyCode{}, gofmt256.FieldFormatter{
+		Marshal: func(v interface{}, width int) (string, error) {
+			return fmt.Sprintf("%0*d", width, v.(CurrencyCode).Numeric), nil
+		},
+		Unmarshal: func(data string) (interface{}, error) {
+			n, err := strconv.Atoi(data)
+			return CurrencyCode{Numeric: n}, err
+		},
+	})
+	alphabetic := gofmt256.WithFieldFormatter(CurrencyCode{}, gofmt256.FieldFormatter{
+		Marshal: func(v interface{}, width int) (string, error) {
+			if v.(CurrencyCode).Numeric == 764 {
+				return "THB", nil
+			}
+			return "", fmt.Errorf("unknown currency %d", v.(CurrencyCode).Numeric)
+		},
+		Unmarshal: func(data string) (interface{}, error) {
+			if data == "THB" {
+				return CurrencyCode{Numeric: 764}, nil
+			}
+			return nil, fmt.Errorf("unknown currency %s", data)
+		},
+	})
+
+	record := FieldMarshalerRecord{Account: "123-4-56789-0", Currency: CurrencyCode{Numeric: 764}}
+
+	tests := []struct {
+		name string
+		opts []gofmt256.Option
+		want string
+	}{
+		{
+			name: "when formatter is registered globally",
+			opts: []gofmt256.Option{gofmt256.WithRecordLength(20)},
+			want: "1234567890764       \n",
+		},
+		{
+			name: "when formatter is given to the builder",
+			opts: []gofmt256.Option{gofmt256.WithRecordLength(20), alphabetic},
+			want: "1234567890THB       \n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := gofmt256.New(record, []FieldMarshalerRecord{}, record, tt.opts...).Build()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want+tt.want, got)
+
+			var header, footer FieldMarshalerRecord
+			var body []FieldMarshalerRecord
+			err = gofmt256.Parse([]byte(got), &header, &body, &footer, tt.opts...)
+			assert.NoError(t, err)
+			assert.Equal(t, record, header)
+		})
+	}
+}
+
+func TestFieldUnmarshalerError(t *testing.T) {
+	var header, footer FieldMarshalerRecord
+	var body []FieldMarshalerRecord
+	data := "123       764       \n"
+	err := gofmt256.Parse([]byte(data+data), &header, &body, &footer, gofmt256.WithRecordLength(20))
+	assert.Error(t, err)
+}
+
+func TestRecordMarshaler(t *testing.T) {
+	header := SequencedRecord{RecordType: "H"}
+	body := []interface{}{
+		FlaggedDetail{Kind: "C", ChequeNo: "1234567"},
+		&FlaggedDetail{Kind: "T", Account: "8888888888"},
+	}
+	footer := SequencedRecord{RecordType: "T"}
+
+	got, err := gofmt256.New(header, body, footer, gofmt256.WithRecordLength(20)).Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "H000001             \nDC1234567           \nDT8888888888        \nT000004             \n", got)
+
+	var parsedHeader, parsedFooter SequencedRecord
+	var parsedBody []FlaggedDetail
+	err = gofmt256.Parse([]byte(got), &parsedHeader, &parsedBody, &parsedFooter, gofmt256.WithRecordLength(20))
+	assert.NoError(t, err)
+	assert.Equal(t, []FlaggedDetail{{Kind: "C", ChequeNo: "1234567"}, {Kind: "T", Account: "8888888888"}}, parsedBody)
+
+	_, err = gofmt256.New(header, []BrokenRecord{{}}, footer, gofmt256.WithRecordLength(20)).Build()
+	assert.Error(t, err)
+}
+
+func TestRecordMarshalerTotals(t *testing.T) {
+	header := SequencedRecord{RecordType: "H"}
+	body := []interface{}{
+		FlaggedDetail{Kind: "C", ChequeNo: "1234567"},
+		SequencedRecord{RecordType: "D"},
+	}
+	opts := []gofmt256.Option{gofmt256.WithRecordLength(20)}
+
+	got, err := gofmt256.New(header, body, CountedTrailer{RecordType: "T"}, opts...).Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "T000002             \n", got[len(got)-21:])
+
+	_, err = gofmt256.New(header, body, SummedTrailer{RecordType: "T"}, opts...).Build()
+	assert.EqualError(t, err, "failed to total body at line 2: [Total] sum field `SequenceNo` is not in gofmt256_test.FlaggedDetail, which marshals its own line")
+
+	// a footer that marshals its own line has no totals
+	got, err = gofmt256.New(header, []SequencedRecord{}, MarshaledTrailer("T000000000          "), opts...).Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "H000001             \nT000000000          \n", got)
+}
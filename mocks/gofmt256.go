@@ -5,6 +5,7 @@
 package gofmt256mocks
 
 import (
+	gofmt256 "github.com/100x-fi/gofmt256"
 	gomock "github.com/golang/mock/gomock"
 	reflect "reflect"
 )
@@ -46,3 +47,55 @@ func (mr *MockBuilderMockRecorder) Build() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Build", reflect.TypeOf((*MockBuilder)(nil).Build))
 }
+
+// MockBatchBuilder is a mock of BatchBuilder interface
+type MockBatchBuilder struct {
+	ctrl     *gomock.Controller
+	recorder *MockBatchBuilderMockRecorder
+}
+
+// MockBatchBuilderMockRecorder is the mock recorder for MockBatchBuilder
+type MockBatchBuilderMockRecorder struct {
+	mock *MockBatchBuilder
+}
+
+// NewMockBatchBuilder creates a new mock instance
+func NewMockBatchBuilder(ctrl *gomock.Controller) *MockBatchBuilder {
+	mock := &MockBatchBuilder{ctrl: ctrl}
+	mock.recorder = &MockBatchBuilderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockBatchBuilder) EXPECT() *MockBatchBuilderMockRecorder {
+	return m.recorder
+}
+
+// Build mocks base method
+func (m *MockBatchBuilder) Build() (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Build")
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Build indicates an expected call of Build
+func (mr *MockBatchBuilderMockRecorder) Build() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Build", reflect.TypeOf((*MockBatchBuilder)(nil).Build))
+}
+
+// AddBatch mocks base method
+func (m *MockBatchBuilder) AddBatch(header, body, trailer interface{}) gofmt256.BatchBuilder {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddBatch", header, body, trailer)
+	ret0, _ := ret[0].(gofmt256.BatchBuilder)
+	return ret0
+}
+
+// AddBatch indicates an expected call of AddBatch
+func (mr *MockBatchBuilderMockRecorder) AddBatch(header, body, trailer interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddBatch", reflect.TypeOf((*MockBatchBuilder)(nil).AddBatch), header, body, trailer)
+}
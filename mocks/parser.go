@@ -0,0 +1,47 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./parser.go
+
+// Package gofmt256mocks is a generated GoMock package.
+package gofmt256mocks
+
+import (
+	gomock "github.com/golang/mock/gomock"
+	reflect "reflect"
+)
+
+// MockParser is a mock of Parser interface
+type MockParser struct {
+	ctrl     *gomock.Controller
+	recorder *MockParserMockRecorder
+}
+
+// MockParserMockRecorder is the mock recorder for MockParser
+type MockParserMockRecorder struct {
+	mock *MockParser
+}
+
+// NewMockParser creates a new mock instance
+func NewMockParser(ctrl *gomock.Controller) *MockParser {
+	mock := &MockParser{ctrl: ctrl}
+	mock.recorder = &MockParserMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockParser) EXPECT() *MockParserMockRecorder {
+	return m.recorder
+}
+
+// Parse mocks base method
+func (m *MockParser) Parse(data []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Parse", data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Parse indicates an expected call of Parse
+func (mr *MockParserMockRecorder) Parse(data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Parse", reflect.TypeOf((*MockParser)(nil).Parse), data)
+}
@@ -0,0 +1,155 @@
+package gofmt256
+
+import "reflect"
+
+const defaultRecordLength = 256
+
+// Option configures a Builder, Parser, Encoder or Decoder.
+type Option func(*options)
+
+type options struct {
+	recordLength   int
+	recordTypes    map[string]reflect.Type
+	recordLayouts  map[string]*Layout
+	bodyLayout     *Layout
+	recordTypeFrom int
+	recordTypeTo   int
+	batchHeader    string
+	batchTrailer   string
+
+	sequenceStart      int
+	sequencePerSection bool
+
+	formatters map[reflect.Type]FieldFormatter
+
+	overflow  Overflow
+	warn      func(Warning)
+	widthUnit WidthUnit
+
+	encoding   *Encoding
+	unmappable Unmappable
+
+	lineEnding      LineEnding
+	trailingNewline bool
+
+	filler       string
+	strictFiller bool
+
+	collectAllErrors bool
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		recordLength:    defaultRecordLength,
+		recordTypes:     make(map[string]reflect.Type),
+		recordLayouts:   make(map[string]*Layout),
+		recordTypeFrom:  1,
+		recordTypeTo:    1,
+		sequenceStart:   1,
+		formatters:      make(map[reflect.Type]FieldFormatter),
+		overflow:        OverflowError,
+		widthUnit:       WidthBytes,
+		unmappable:      UnmappableError,
+		lineEnding:      LineEndingLF,
+		trailingNewline: true,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithRecordLength sets the length of every line in the file. It defaults
+// to 256.
+func WithRecordLength(length int) Option {
+	return func(o *options) {
+		o.recordLength = length
+	}
+}
+
+// WithRecordType registers the struct type of v, or v itself when it is a
+// *Layout, as the layout of lines whose record type is code. Registered types
+// are used to parse a body of []interface{} or []LayoutRecord and by
+// Decoder.DecodeRecord.
+func WithRecordType(code string, v interface{}) Option {
+	return func(o *options) {
+		if l, ok := v.(*Layout); ok {
+			o.recordLayouts[code] = l
+			return
+		}
+		t := reflect.TypeOf(v)
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		o.recordTypes[code] = t
+	}
+}
+
+// WithRecordTypePosition sets the position of the record type within a line.
+// It defaults to position 1.
+func WithRecordTypePosition(from, to int) Option {
+	return func(o *options) {
+		o.recordTypeFrom = from
+		o.recordTypeTo = to
+	}
+}
+
+// WithBatchRecordTypes sets the record types of batch headers and batch
+// trailers, which are required to parse a body of []Batch.
+func WithBatchRecordTypes(header, trailer string) Option {
+	return func(o *options) {
+		o.batchHeader = header
+		o.batchTrailer = trailer
+	}
+}
+
+// WithSequenceStart sets the first number given to fields tagged with `seq`.
+// It defaults to 1.
+func WithSequenceStart(start int) Option {
+	return func(o *options) {
+		o.sequenceStart = start
+	}
+}
+
+// WithSequencePerSection restarts the numbering of fields tagged with `seq`
+// at the header, the body, every batch and the footer, instead of running it
+// across the whole file.
+func WithSequencePerSection() Option {
+	return func(o *options) {
+		o.sequencePerSection = true
+	}
+}
+
+// WithFieldFormatter registers f for every field of the type of v, taking
+// precedence over RegisterFieldFormatter.
+func WithFieldFormatter(v interface{}, f FieldFormatter) Option {
+	return func(o *options) {
+		o.formatters[reflect.TypeOf(v)] = f
+	}
+}
+
+// WithOverflow sets the overflow policy of fields without an `overflow`
+// subtag. It defaults to OverflowError, which fields tagged with `seq`, `agg`
+// or `decimals` keep whatever the policy.
+func WithOverflow(overflow Overflow) Option {
+	return func(o *options) {
+		o.overflow = overflow
+	}
+}
+
+// WithWarningFunc sets a function that is called for every value changed to
+// fit its field.
+func WithWarningFunc(fn func(Warning)) Option {
+	return func(o *options) {
+		o.warn = fn
+	}
+}
+
+// WithCollectAllErrors makes building and parsing carry on past invalid
+// records and fields, and return every ValidationError at once in a
+// MultiError.
+func WithCollectAllErrors() Option {
+	return func(o *options) {
+		o.collectAllErrors = true
+	}
+}
@@ -0,0 +1,82 @@
+package gofmt256
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Overflow decides what happens to a value that is longer than its field.
+type Overflow string
+
+const (
+	// OverflowError fails the line. It is the default.
+	OverflowError Overflow = "error"
+	// OverflowTruncate keeps the beginning of the value.
+	OverflowTruncate Overflow = "truncate"
+	// OverflowTruncateLeft keeps the end of the value.
+	OverflowTruncateLeft Overflow = "truncate-left"
+	// OverflowEllipsis keeps the beginning of the value and ends it with "...".
+	OverflowEllipsis Overflow = "ellipsis"
+)
+
+const ellipsis = "..."
+
+// Warning reports a value that was changed to fit its field.
+type Warning struct {
+	Section string
+	Line    int
+	Field   string
+	Value   string
+	Result  string
+}
+
+func extractOverflow(value string) (Overflow, error) {
+	switch o := Overflow(value); o {
+	case OverflowError, OverflowTruncate, OverflowTruncateLeft, OverflowEllipsis:
+		return o, nil
+	}
+	return "", errors.Errorf("`overflow` must be one of error, truncate, truncate-left or ellipsis, got `%s`", value)
+}
+
+// fit shortens data that is longer than the field according to the overflow
+// policy of the field, falling back to the policy of the builder. Sequence
+// numbers, totals and amounts fall back to OverflowError instead, as a
+// shortened number is a wrong number.
+func fit(fs FieldStruct, data string, ctx lineContext) string {
+	width := fs.to - fs.from + 1
+	unit := ctx.opts.unit()
+	if unit.Width(data) <= width {
+		return data
+	}
+
+	policy := fs.overflow
+	if policy == "" && !fs.seq && fs.agg == "" && fs.decimals < 0 {
+		policy = ctx.opts.overflow
+	}
+
+	var result string
+	switch policy {
+	case OverflowTruncate:
+		result = unit.head(data, width)
+	case OverflowTruncateLeft:
+		result = unit.tail(data, width)
+	case OverflowEllipsis:
+		if width <= len(ellipsis) {
+			result = unit.head(data, width)
+		} else {
+			result = unit.head(data, width-len(ellipsis)) + ellipsis
+		}
+	default:
+		return data
+	}
+
+	if ctx.opts.warn != nil {
+		ctx.opts.warn(Warning{
+			Section: ctx.section,
+			Line:    ctx.line,
+			Field:   fs.Name,
+			Value:   data,
+			Result:  result,
+		})
+	}
+	return result
+}
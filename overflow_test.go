@@ -0,0 +1,124 @@
+package gofmt256_test
+
+import (
+	"testing"
+
+	"github.com/100x-fi/gofmt256"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverflow(t *testing.T) {
+	record := OverflowRecord{RecordType: "D", Name: "Somchai Jaidee", Reference: "INV-000123", Note: "ok"}
+
+	tests := []struct {
+		name    string
+		opts    []gofmt256.Option
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "error by default",
+			wantErr: true,
+		},
+		{
+			name: "truncate",
+			opts: []gofmt256.Option{gofmt256.WithOverflow(gofmt256.OverflowTruncate)},
+			want: "DSomchai 000123ok   ",
+		},
+		{
+			name: "ellipsis",
+			opts: []gofmt256.Option{gofmt256.WithOverflow(gofmt256.OverflowEllipsis)},
+			want: "DSomch...000123ok   ",
+		},
+		{
+			name: "truncate left",
+			opts: []gofmt256.Option{gofmt256.WithOverflow(gofmt256.OverflowTruncateLeft)},
+			want: "Di Jaidee000123ok   ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]gofmt256.Option{gofmt256.WithRecordLength(20)}, tt.opts...)
+			got, err := gofmt256.New(record, []OverflowRecord{}, record, opts...).Build()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want+"\n"+tt.want+"\n", got)
+		})
+	}
+}
+
+func TestOverflowFieldPolicy(t *testing.T) {
+	record := OverflowRecord{RecordType: "D", Note: "too long"}
+	_, err := gofmt256.New(record, []OverflowRecord{}, record,
+		gofmt256.WithRecordLength(20), gofmt256.WithOverflow(gofmt256.OverflowTruncate)).Build()
+	assert.Error(t, err)
+}
+
+func TestOverflowNumbers(t *testing.T) {
+	header := NarrowDetail{RecordType: "H"}
+	footer := NarrowTrailer{RecordType: "T"}
+
+	tests := []struct {
+		name    string
+		body    []NarrowDetail
+		opts    []gofmt256.Option
+		wantErr bool
+	}{
+		{
+			name: "fits",
+			body: []NarrowDetail{{RecordType: "D", Amount: 600}},
+		},
+		{
+			name:    "total",
+			body:    []NarrowDetail{{RecordType: "D", Amount: 600}, {RecordType: "D", Amount: 600}},
+			wantErr: true,
+		},
+		{
+			name:    "sequence number",
+			body:    []NarrowDetail{{RecordType: "D", Amount: 1}},
+			opts:    []gofmt256.Option{gofmt256.WithSequenceStart(99)},
+			wantErr: true,
+		},
+		{
+			name:    "amount",
+			body:    []NarrowDetail{{RecordType: "D", Amount: 123456}},
+			wantErr: true,
+		},
+	}
+	for _, overflow := range []gofmt256.Overflow{gofmt256.OverflowTruncate, gofmt256.OverflowTruncateLeft, gofmt256.OverflowEllipsis} {
+		for _, tt := range tests {
+			t.Run(string(overflow)+"/"+tt.name, func(t *testing.T) {
+				opts := append([]gofmt256.Option{gofmt256.WithRecordLength(20), gofmt256.WithOverflow(overflow)}, tt.opts...)
+				_, err := gofmt256.New(header, tt.body, footer, opts...).Build()
+				if tt.wantErr {
+					assert.Error(t, err)
+					return
+				}
+				assert.NoError(t, err)
+			})
+		}
+	}
+}
+
+func TestOverflowWarnings(t *testing.T) {
+	header := OverflowRecord{RecordType: "H"}
+	body := []OverflowRecord{{RecordType: "D", Name: "Somchai Jaidee", Reference: "INV-000123"}}
+	footer := OverflowRecord{RecordType: "T"}
+
+	var warnings []gofmt256.Warning
+	_, err := gofmt256.New(header, body, footer,
+		gofmt256.WithRecordLength(20),
+		gofmt256.WithOverflow(gofmt256.OverflowTruncate),
+		gofmt256.WithWarningFunc(func(w gofmt256.Warning) {
+			warnings = append(warnings, w)
+		}),
+	).Build()
+	assert.NoError(t, err)
+	assert.Equal(t, []gofmt256.Warning{
+		{Section: "body", Line: 2, Field: "Name", Value: "Somchai Jaidee", Result: "Somchai "},
+		{Section: "body", Line: 2, Field: "Reference", Value: "INV-000123", Result: "000123"},
+	}, warnings)
+}
@@ -0,0 +1,383 @@
+package gofmt256
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type Parser interface {
+	Parse(data []byte) error
+}
+
+type parser struct {
+	header interface{}
+	body   interface{}
+	footer interface{}
+	opts   options
+}
+
+func NewParser(header, body, footer interface{}, opts ...Option) Parser {
+	return &parser{
+		header: header,
+		body:   body,
+		footer: footer,
+		opts:   newOptions(opts),
+	}
+}
+
+// Parse reads a format 256 bytes file into header, body and footer. Header and
+// footer must be pointers to struct and body must be a pointer to slice.
+func Parse(data []byte, header, body, footer interface{}, opts ...Option) error {
+	return NewParser(header, body, footer, opts...).Parse(data)
+}
+
+func (p *parser) Parse(data []byte) error {
+	headerValue := reflect.ValueOf(p.header)
+	if headerValue.Kind() != reflect.Ptr || (headerValue.Elem().Kind() != reflect.Struct && !headerValue.Type().Implements(recordUnmarshalerType)) {
+		return errors.New("header must be pointer to struct")
+	}
+
+	bodyValue := reflect.ValueOf(p.body)
+	if bodyValue.Kind() != reflect.Ptr || bodyValue.Elem().Kind() != reflect.Slice {
+		return errors.New("body must be pointer to slice")
+	}
+
+	footerValue := reflect.ValueOf(p.footer)
+	if footerValue.Kind() != reflect.Ptr || (footerValue.Elem().Kind() != reflect.Struct && !footerValue.Type().Implements(recordUnmarshalerType)) {
+		return errors.New("footer must be pointer to struct")
+	}
+
+	lineCount, err := countLines(data, p.opts)
+	if err != nil {
+		return err
+	}
+	if lineCount < 2 {
+		return errors.New("data must contain at least header and footer")
+	}
+
+	footerRecord, err := recordValue(footerValue.Elem(), p.opts)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse footer")
+	}
+	totals, err := newTally(footerRecord.Type(), p.opts)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse footer")
+	}
+
+	decoder := newDecoder(bytes.NewReader(data), p.opts)
+	seq := newSequence(p.opts)
+	c := &collector{all: p.opts.collectAllErrors}
+	decoder.Next()
+	seq.section()
+	err = decoder.Decode(p.header)
+	if err := p.check(c, decoder, &seq, headerValue.Elem(), err, SectionHeader, 0); err != nil {
+		return err
+	}
+
+	if bodyValue.Elem().Type().Elem() == reflect.TypeOf(Batch{}) {
+		batches, err := p.parseBatches(decoder, &seq, c, totals, lineCount)
+		if err != nil {
+			if isValidationError(err) {
+				return err
+			}
+			return errors.Wrap(err, "failed to parse batch")
+		}
+		bodyValue.Elem().Set(reflect.ValueOf(batches))
+	} else if err := p.parseBody(decoder, &seq, c, totals, lineCount, bodyValue.Elem()); err != nil {
+		return err
+	}
+
+	seq.section()
+	err = decoder.Decode(p.footer)
+	if err := p.check(c, decoder, &seq, footerValue.Elem(), err, SectionFooter, 0); err != nil {
+		return err
+	}
+	// totals of a file with invalid records cannot be trusted
+	if c.err() == nil {
+		if err := p.fail(c, decoder, totals.verify(footerValue.Elem()), SectionFooter, 0); err != nil {
+			return err
+		}
+	}
+
+	return c.err()
+}
+
+func (p *parser) parseBody(decoder *Decoder, seq *sequence, c *collector, totals *tally, lineCount int, bodyValue reflect.Value) error {
+	sliceValue := reflect.MakeSlice(bodyValue.Type(), 0, lineCount-2)
+	elemType := sliceValue.Type().Elem()
+	seq.section()
+	for decoder.Next() && decoder.Line() < lineCount {
+		index := decoder.Line() - 2
+		if elemType.Kind() == reflect.Interface || elemType == layoutRecordType {
+			record, decodeErr := decoder.DecodeRecord()
+			if err := p.check(c, decoder, seq, reflect.ValueOf(record), decodeErr, SectionBody, index); err != nil {
+				return err
+			}
+			if decodeErr != nil {
+				continue
+			}
+			if err := p.fail(c, decoder, totals.add(reflect.ValueOf(record)), SectionBody, index); err != nil {
+				return err
+			}
+			sliceValue = reflect.Append(sliceValue, reflect.ValueOf(record))
+			continue
+		}
+
+		elem := reflect.New(elemType).Elem()
+		target := elem.Addr()
+		if elemType.Kind() == reflect.Ptr {
+			elem.Set(reflect.New(elemType.Elem()))
+			target = elem
+		}
+		decodeErr := decoder.Decode(target.Interface())
+		if err := p.check(c, decoder, seq, target.Elem(), decodeErr, SectionBody, index); err != nil {
+			return err
+		}
+		if decodeErr != nil {
+			continue
+		}
+		if err := p.fail(c, decoder, totals.add(target.Elem()), SectionBody, index); err != nil {
+			return err
+		}
+		sliceValue = reflect.Append(sliceValue, elem)
+	}
+	if err := decoder.Err(); err != nil {
+		return err
+	}
+	bodyValue.Set(sliceValue)
+	return nil
+}
+
+func (p *parser) parseBatches(decoder *Decoder, seq *sequence, c *collector, totals *tally, lineCount int) ([]Batch, error) {
+	if p.opts.batchHeader == "" || p.opts.batchTrailer == "" {
+		return nil, errors.New("batch record types must be set with WithBatchRecordTypes")
+	}
+
+	batches := []Batch{}
+	batchTotals := make(map[reflect.Type]*tally)
+	var batch *Batch
+	var records []interface{}
+	recordIndex := 0
+	for decoder.Next() && decoder.Line() < lineCount {
+		recordType := decoder.RecordType()
+		switch {
+		case recordType == p.opts.batchHeader && batch != nil:
+			return nil, errors.Errorf("line %d, offset %d: batch header found before the trailer of the previous batch", decoder.Line(), decoder.Offset())
+		case recordType == p.opts.batchTrailer && batch == nil:
+			return nil, errors.Errorf("line %d, offset %d: batch trailer found without batch header", decoder.Line(), decoder.Offset())
+		case recordType != p.opts.batchHeader && batch == nil:
+			return nil, errors.Errorf("line %d, offset %d: record found outside of a batch", decoder.Line(), decoder.Offset())
+		}
+
+		section, index := SectionBody, recordIndex
+		switch recordType {
+		case p.opts.batchHeader:
+			section, index = SectionBatchHeader, len(batches)
+			seq.section()
+		case p.opts.batchTrailer:
+			section, index = SectionBatchTrailer, len(batches)
+		default:
+			recordIndex++
+		}
+		record, decodeErr := decoder.DecodeRecord()
+		if err := p.check(c, decoder, seq, reflect.ValueOf(record), decodeErr, section, index); err != nil {
+			return nil, err
+		}
+		switch recordType {
+		case p.opts.batchHeader:
+			batch = &Batch{Header: record}
+			records = []interface{}{}
+		case p.opts.batchTrailer:
+			if c.err() == nil {
+				err := p.verifyBatchTotals(batchTotals, records, reflect.ValueOf(record))
+				if err := p.fail(c, decoder, err, section, index); err != nil {
+					return nil, err
+				}
+			}
+			batch.Body = records
+			batch.Trailer = record
+			batches = append(batches, *batch)
+			batch = nil
+		default:
+			if decodeErr != nil {
+				continue
+			}
+			if err := p.fail(c, decoder, totals.add(reflect.ValueOf(record)), section, index); err != nil {
+				return nil, err
+			}
+			records = append(records, record)
+		}
+	}
+	if err := decoder.Err(); err != nil {
+		return nil, err
+	}
+	if batch != nil {
+		return nil, errors.New("last batch is not closed by a batch trailer")
+	}
+	return batches, nil
+}
+
+func (p *parser) verifyBatchTotals(batchTotals map[reflect.Type]*tally, records []interface{}, trailer reflect.Value) error {
+	trailer, err := recordValue(trailer, p.opts)
+	if err != nil {
+		return err
+	}
+	t, ok := batchTotals[trailer.Type()]
+	if !ok {
+		var err error
+		t, err = newTally(trailer.Type(), p.opts)
+		if err != nil {
+			return err
+		}
+		batchTotals[trailer.Type()] = t
+	}
+
+	t.reset()
+	for _, record := range records {
+		if err := t.add(reflect.ValueOf(record)); err != nil {
+			return err
+		}
+	}
+	return t.verify(trailer)
+}
+
+// check finishes reading a record of section: unless decoding it failed with
+// err, it verifies the sequence number of the record.
+func (p *parser) check(c *collector, decoder *Decoder, seq *sequence, record reflect.Value, err error, section string, index int) error {
+	want := seq.next
+	seq.next++
+	if err == nil {
+		err = verifySequence(record, want, p.opts)
+	}
+	return p.fail(c, decoder, err, section, index)
+}
+
+// fail locates a validation error at the current line and collects or
+// returns it. Other errors are returned wrapped.
+func (p *parser) fail(c *collector, decoder *Decoder, err error, section string, index int) error {
+	if err == nil {
+		return nil
+	}
+	at := ValidationError{Section: section, Index: index, Line: decoder.Line(), Offset: decoder.Offset()}
+	if !locate(err, at) {
+		return errors.Wrapf(err, "failed to parse %s", section)
+	}
+	return c.check(err)
+}
+
+func parseLine(line string, output reflect.Value, opts options) error {
+	r := opts.unit().ruler(line)
+	if r.width() != opts.recordLength {
+		return lineError(line, ErrLineLength, errors.Errorf("line must be %d %s long", opts.recordLength, opts.widthUnit))
+	}
+
+	schema, err := compile(output.Type(), opts)
+	if err != nil {
+		return err
+	}
+	var errs MultiError
+	for _, fs := range schema.fields {
+		data, _ := r.field(fs.from, fs.to)
+		if fs.filler {
+			if problem := fillerProblem(data, opts); problem != "" {
+				err := fieldError(fs, data, ErrPadding, errors.New(problem))
+				if !opts.collectAllErrors {
+					return err
+				}
+				errs = append(errs, err)
+			}
+			continue
+		}
+		fs.Data = unpad(fs, data)
+		if err := setFieldData(fs, output.Field(fs.index), fs.Data, opts); err != nil {
+			err := fieldError(fs, data, ErrInvalidValue, errors.Wrap(err, "unable to set field"))
+			if !opts.collectAllErrors {
+				return err
+			}
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func unpad(fs FieldStruct, data string) string {
+	if fs.padding == "" {
+		return data
+	}
+	if fs.align == "R" {
+		return strings.TrimLeft(data, fs.padding)
+	}
+	return strings.TrimRight(data, fs.padding)
+}
+
+// setFieldData converts unpadded data to the value of a field according to
+// its tag.
+func setFieldData(fs FieldStruct, field reflect.Value, data string, opts options) error {
+	if ok, err := unmarshalField(field, data, opts); ok {
+		return err
+	}
+	switch {
+	case fs.decimals >= 0:
+		return setDecimal(fs, field, data)
+	case fs.timeLayout != "":
+		return setTime(fs, field, data)
+	}
+	return setField(field, data)
+}
+
+func setField(field reflect.Value, data string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(data)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if data == "" {
+			field.SetInt(0)
+			return nil
+		}
+		n, err := strconv.ParseInt(data, 10, field.Type().Bits())
+		if err != nil {
+			return errors.Wrapf(err, "unable to convert `%s` to `%s`", data, field.Type())
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if data == "" {
+			field.SetUint(0)
+			return nil
+		}
+		n, err := strconv.ParseUint(data, 10, field.Type().Bits())
+		if err != nil {
+			return errors.Wrapf(err, "unable to convert `%s` to `%s`", data, field.Type())
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if data == "" {
+			field.SetFloat(0)
+			return nil
+		}
+		f, err := strconv.ParseFloat(data, field.Type().Bits())
+		if err != nil {
+			return errors.Wrapf(err, "unable to convert `%s` to `%s`", data, field.Type())
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		if data == "" {
+			field.SetBool(false)
+			return nil
+		}
+		b, err := strconv.ParseBool(data)
+		if err != nil {
+			return errors.Wrapf(err, "unable to convert `%s` to `%s`", data, field.Type())
+		}
+		field.SetBool(b)
+	default:
+		return errors.Errorf("unsupported field type `%s`", field.Type())
+	}
+	return nil
+}
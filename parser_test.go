@@ -0,0 +1,352 @@
+package gofmt256_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/100x-fi/gofmt256"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	file, err := gofmt256.New(getSubMerchantReportHeader(), getSubMerchantReportBody(), getSubMerchantReportFooter()).Build()
+	if err != nil {
+		t.Fatalf("gofmt256.Build() err %v", err)
+	}
+
+	wantFooter := getSubMerchantReportFooter()
+	// TotalDebitAmount is a string padded with '0', so its zeroes are stripped
+	wantFooter.TotalDebitAmount = ""
+
+	tests := []struct {
+		name       string
+		data       string
+		wantHeader SubMerchantReportHeader
+		wantBody   []SubMerchantReportBody
+		wantFooter SubMerchantReportFooter
+		wantError  bool
+	}{
+		{
+			name:       "when parse format 256 bytes with body successfully",
+			data:       file,
+			wantHeader: getSubMerchantReportHeader(),
+			wantBody:   getSubMerchantReportBody(),
+			wantFooter: wantFooter,
+			wantError:  false,
+		},
+		{
+			name:       "when parse format 256 bytes without trailing new line successfully",
+			data:       file[:len(file)-1],
+			wantHeader: getSubMerchantReportHeader(),
+			wantBody:   getSubMerchantReportBody(),
+			wantFooter: wantFooter,
+			wantError:  false,
+		},
+		{
+			name:      "when there is only one line",
+			data:      file[:257],
+			wantError: true,
+		},
+		{
+			name:      "when line is shorter than 256 bytes",
+			data:      file[:100] + "\n" + file[257:],
+			wantError: true,
+		},
+		{
+			name:      "when int field is not a number",
+			data:      "HABCDEF" + file[7:],
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var header SubMerchantReportHeader
+			var body []SubMerchantReportBody
+			var footer SubMerchantReportFooter
+			err := gofmt256.NewParser(&header, &body, &footer).Parse([]byte(tt.data))
+			if (err != nil) != tt.wantError {
+				t.Errorf("gofmt256.Parse() err %v, wantErr %v", err, tt.wantError)
+			}
+			if tt.wantError {
+				return
+			}
+			assert.Equal(t, tt.wantHeader, header)
+			assert.Equal(t, tt.wantBody, body)
+			assert.Equal(t, tt.wantFooter, footer)
+		})
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	file, err := gofmt256.New(getSubMerchantReportHeader(), getSubMerchantReportBody(), getSubMerchantReportFooter()).Build()
+	if err != nil {
+		t.Fatalf("gofmt256.Build() err %v", err)
+	}
+
+	var header SubMerchantReportHeader
+	var body []SubMerchantReportBody
+	var bodyPtr []*SubMerchantReportBody
+	var footer SubMerchantReportFooter
+
+	tests := []struct {
+		name      string
+		header    interface{}
+		body      interface{}
+		footer    interface{}
+		wantError bool
+	}{
+		{
+			name:      "when body is a slice of pointer",
+			header:    &header,
+			body:      &bodyPtr,
+			footer:    &footer,
+			wantError: false,
+		},
+		{
+			name:      "when header is not a pointer",
+			header:    header,
+			body:      &body,
+			footer:    &footer,
+			wantError: true,
+		},
+		{
+			name:      "when body is not a pointer to slice",
+			header:    &header,
+			body:      body,
+			footer:    &footer,
+			wantError: true,
+		},
+		{
+			name:      "when footer is not a pointer to struct",
+			header:    &header,
+			body:      &body,
+			footer:    &body,
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := gofmt256.Parse([]byte(file), tt.header, tt.body, tt.footer)
+			if (err != nil) != tt.wantError {
+				t.Errorf("gofmt256.Parse() err %v, wantErr %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestParseWithRecordLength(t *testing.T) {
+	wantHeader := ShortRecordHeader{RecordType: "H", BankCode: "888"}
+	wantBody := []ShortRecordBody{{RecordType: "D", Account: "8888888888", Amount: 51500}}
+	wantFooter := ShortRecordFooter{RecordType: "T", Count: 1}
+
+	file, err := gofmt256.New(wantHeader, wantBody, wantFooter, gofmt256.WithRecordLength(94)).Build()
+	if err != nil {
+		t.Fatalf("gofmt256.Build() err %v", err)
+	}
+
+	var header ShortRecordHeader
+	var body []ShortRecordBody
+	var footer ShortRecordFooter
+	err = gofmt256.Parse([]byte(file), &header, &body, &footer, gofmt256.WithRecordLength(94))
+	assert.NoError(t, err)
+	assert.Equal(t, wantHeader, header)
+	assert.Equal(t, wantBody, body)
+	assert.Equal(t, wantFooter, footer)
+
+	err = gofmt256.Parse([]byte(file), &header, &body, &footer)
+	assert.Error(t, err)
+}
+
+func TestParseWithRecordTypes(t *testing.T) {
+	file, err := gofmt256.New(getSubMerchantReportHeader(), getMixedSubMerchantReportBody(), getSubMerchantReportFooter()).Build()
+	if err != nil {
+		t.Fatalf("gofmt256.Build() err %v", err)
+	}
+
+	bodyRecords := getSubMerchantReportBody()
+	wantBody := []interface{}{
+		bodyRecords[0],
+		SubMerchantReportAddenda{RecordType: "A", SequenceNo: 3, Info: "first addenda"},
+		bodyRecords[1],
+		bodyRecords[2],
+	}
+
+	var header SubMerchantReportHeader
+	var body []interface{}
+	var footer SubMerchantReportFooter
+	err = gofmt256.Parse([]byte(file), &header, &body, &footer,
+		gofmt256.WithRecordType("D", SubMerchantReportBody{}),
+		gofmt256.WithRecordType("A", SubMerchantReportAddenda{}),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, wantBody, body)
+
+	err = gofmt256.Parse([]byte(file), &header, &body, &footer,
+		gofmt256.WithRecordType("D", SubMerchantReportBody{}),
+	)
+	assert.Error(t, err)
+}
+
+func TestParseWithBatches(t *testing.T) {
+	file, err := gofmt256.New(getSubMerchantReportHeader(), getSubMerchantReportBatches(), getSubMerchantReportFooter()).Build()
+	if err != nil {
+		t.Fatalf("gofmt256.Build() err %v", err)
+	}
+	lines := strings.SplitAfter(file, "\n")
+
+	tests := []struct {
+		name      string
+		data      string
+		opts      []gofmt256.Option
+		want      []gofmt256.Batch
+		wantError bool
+	}{
+		{
+			name:      "when parse batches successfully",
+			data:      file,
+			want:      getSubMerchantReportBatches(),
+			wantError: false,
+		},
+		{
+			name:      "when batch record types are not set",
+			data:      file,
+			opts:      []gofmt256.Option{},
+			wantError: true,
+		},
+		{
+			name:      "when batch is not closed by a trailer",
+			data:      strings.Join(append(lines[:4:4], lines[8]), ""),
+			wantError: true,
+		},
+		{
+			name:      "when record is outside of a batch",
+			data:      strings.Join(append(lines[:5:5], lines[6:]...), ""),
+			wantError: true,
+		},
+		{
+			name:      "when batch trailer has no batch header",
+			data:      strings.Join(append(lines[:1:1], lines[4:]...), ""),
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			if opts == nil {
+				opts = []gofmt256.Option{
+					gofmt256.WithBatchRecordTypes("B", "C"),
+					gofmt256.WithRecordType("B", SubMerchantReportBatchHeader{}),
+					gofmt256.WithRecordType("D", SubMerchantReportBody{}),
+					gofmt256.WithRecordType("C", SubMerchantReportBatchTrailer{}),
+				}
+			}
+
+			var header SubMerchantReportHeader
+			var batches []gofmt256.Batch
+			var footer SubMerchantReportFooter
+			err := gofmt256.Parse([]byte(tt.data), &header, &batches, &footer, opts...)
+			if (err != nil) != tt.wantError {
+				t.Errorf("gofmt256.Parse() err %v, wantErr %v", err, tt.wantError)
+			}
+			if tt.wantError {
+				return
+			}
+			assert.Equal(t, tt.want, batches)
+		})
+	}
+}
+
+func TestParseWithSequence(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		opts      []gofmt256.Option
+		wantError bool
+	}{
+		{
+			name:      "when sequence is contiguous",
+			data:      "H000001             \nD000002             \nD000003             \nT000004             \n",
+			wantError: false,
+		},
+		{
+			name:      "when sequence has a gap",
+			data:      "H000001             \nD000002             \nD000004             \nT000005             \n",
+			wantError: true,
+		},
+		{
+			name:      "when sequence restarts per section",
+			data:      "H000001             \nD000001             \nD000002             \nT000001             \n",
+			opts:      []gofmt256.Option{gofmt256.WithSequencePerSection()},
+			wantError: false,
+		},
+		{
+			name:      "when sequence does not restart per section",
+			data:      "H000001             \nD000002             \nD000003             \nT000004             \n",
+			opts:      []gofmt256.Option{gofmt256.WithSequencePerSection()},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var header SequencedRecord
+			var body []SequencedRecord
+			var footer SequencedRecord
+			opts := append([]gofmt256.Option{gofmt256.WithRecordLength(20)}, tt.opts...)
+			err := gofmt256.Parse([]byte(tt.data), &header, &body, &footer, opts...)
+			if (err != nil) != tt.wantError {
+				t.Errorf("gofmt256.Parse() err %v, wantErr %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestParseWithAggregates(t *testing.T) {
+	file, err := gofmt256.New(getSubMerchantReportHeader(), getSubMerchantReportBody(), getSubMerchantReportAggregatedFooter()).Build()
+	if err != nil {
+		t.Fatalf("gofmt256.Build() err %v", err)
+	}
+	batchFile, err := gofmt256.New(getSubMerchantReportHeader(), getSubMerchantReportBatches(), getSubMerchantReportAggregatedFooter()).Build()
+	if err != nil {
+		t.Fatalf("gofmt256.Build() err %v", err)
+	}
+	footerAt := len(file) - 257
+
+	var header SubMerchantReportHeader
+	var footer SubMerchantReportAggregatedFooter
+
+	var body []SubMerchantReportBody
+	err = gofmt256.Parse([]byte(file), &header, &body, &footer)
+	assert.NoError(t, err)
+	assert.Equal(t, "5678200", footer.TotalCreditAmount)
+	assert.Equal(t, 3, footer.TotalCreditTransaction)
+
+	mismatch := file[:footerAt+57] + "4" + file[footerAt+58:]
+	err = gofmt256.Parse([]byte(mismatch), &header, &body, &footer)
+	var mismatchErr *gofmt256.AggregateMismatchError
+	if assert.True(t, errors.As(err, &mismatchErr)) {
+		assert.Equal(t, "TotalCreditTransaction", mismatchErr.Field)
+		assert.Equal(t, int64(3), mismatchErr.Want)
+		assert.Equal(t, int64(4), mismatchErr.Got)
+	}
+
+	opts := []gofmt256.Option{
+		gofmt256.WithBatchRecordTypes("B", "C"),
+		gofmt256.WithRecordType("B", SubMerchantReportBatchHeader{}),
+		gofmt256.WithRecordType("D", SubMerchantReportBody{}),
+		gofmt256.WithRecordType("C", SubMerchantReportBatchTrailer{}),
+	}
+	var batches []gofmt256.Batch
+	err = gofmt256.Parse([]byte(batchFile), &header, &batches, &footer, opts...)
+	assert.NoError(t, err)
+
+	// the trailer of the first batch claims 3 records instead of 2
+	mismatch = batchFile[:257*4+12] + "3" + batchFile[257*4+13:]
+	err = gofmt256.Parse([]byte(mismatch), &header, &batches, &footer, opts...)
+	assert.True(t, errors.As(err, &mismatchErr))
+	assert.True(t, errors.Is(err, gofmt256.ErrAggregateMismatch))
+}
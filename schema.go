@@ -0,0 +1,190 @@
+package gofmt256
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Schema is the compiled layout of a struct type: its fields in the order
+// they appear on a line, checked against the record length. A schema is built
+// once per type and record length and shared by every line of that type.
+type Schema struct {
+	Type         reflect.Type
+	RecordLength int
+
+	fields []FieldStruct
+	byName map[string]FieldStruct
+}
+
+// SchemaField describes a field of a Schema. Filler is true for the ranges
+// that no field covers, named "filler", in a schema compiled with WithFiller.
+type SchemaField struct {
+	Name    string
+	From    int
+	To      int
+	Align   string
+	Padding string
+	Filler  bool
+}
+
+type schemaKey struct {
+	t            reflect.Type
+	recordLength int
+	filled       bool
+	unit         WidthUnit
+}
+
+var schemas sync.Map
+
+// Compile checks the tags of the struct type of v, or the fields of v when it
+// is a *Layout, and caches its schema, so layouts can be validated at program
+// start-up. Options other than the record length and WithFiller do not affect
+// the schema.
+func Compile(v interface{}, opts ...Option) (*Schema, error) {
+	if l, ok := v.(*Layout); ok {
+		return l.compile(newOptions(opts))
+	}
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, errors.New("schema must be compiled for struct")
+	}
+	return compile(t, newOptions(opts))
+}
+
+// MustCompile is like Compile but panics if the layout is invalid.
+func MustCompile(v interface{}, opts ...Option) *Schema {
+	s, err := Compile(v, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func compile(t reflect.Type, opts options) (*Schema, error) {
+	if err := checkFiller(opts); err != nil {
+		return nil, err
+	}
+	key := schemaKey{t: t, recordLength: opts.recordLength, filled: opts.filler != "", unit: opts.unit()}
+	if s, ok := schemas.Load(key); ok {
+		return s.(*Schema), nil
+	}
+
+	fields, err := layout(t, opts)
+	if err != nil {
+		return nil, err
+	}
+	return storeSchema(key, fields), nil
+}
+
+// storeSchema caches the schema of key made of fields, unless one is already
+// cached.
+func storeSchema(key schemaKey, fields []FieldStruct) *Schema {
+	s := &Schema{
+		Type:         key.t,
+		RecordLength: key.recordLength,
+		fields:       fields,
+		byName:       make(map[string]FieldStruct, len(fields)),
+	}
+	for _, fs := range fields {
+		if !fs.filler {
+			s.byName[fs.Name] = fs
+		}
+	}
+
+	actual, _ := schemas.LoadOrStore(key, s)
+	return actual.(*Schema)
+}
+
+// Fields returns the fields of s in the order they appear on a line.
+func (s *Schema) Fields() []SchemaField {
+	fields := make([]SchemaField, len(s.fields))
+	for i, fs := range s.fields {
+		fields[i] = SchemaField{
+			Name:    fs.Name,
+			From:    fs.from,
+			To:      fs.to,
+			Align:   fs.align,
+			Padding: fs.padding,
+			Filler:  fs.filler,
+		}
+	}
+	return fields
+}
+
+// FieldValue is a field of a line as found in a file. Problem describes why
+// the field is invalid, if it is, and Err is ErrLineLength, ErrPadding or
+// ErrInvalidValue accordingly.
+type FieldValue struct {
+	Name    string
+	From    int
+	To      int
+	Raw     string
+	Trimmed string
+	Problem string
+	Err     error
+}
+
+// Inspect splits line into the fields of s, reporting fields that are not
+// padded according to their alignment or cannot be read into their type.
+// Options such as WithWidthUnit change how fields are read.
+func (s *Schema) Inspect(line string, opts ...Option) []FieldValue {
+	o := newOptions(opts)
+	r := o.unit().ruler(line)
+	values := make([]FieldValue, 0, len(s.fields))
+	for _, fs := range s.fields {
+		fv := FieldValue{Name: fs.Name, From: fs.from, To: fs.to}
+		var ok bool
+		if fv.Raw, ok = r.field(fs.from, fs.to); !ok {
+			fv.Problem, fv.Err = "line is too short", ErrLineLength
+			values = append(values, fv)
+			continue
+		}
+
+		if fs.filler {
+			if fv.Problem = fillerProblem(fv.Raw, o); fv.Problem != "" {
+				fv.Err = ErrPadding
+			}
+			values = append(values, fv)
+			continue
+		}
+		fv.Trimmed = unpad(fs, fv.Raw)
+		if fv.Problem = paddingProblem(fs, fv.Trimmed); fv.Problem != "" {
+			fv.Err = ErrPadding
+		} else {
+			field := reflect.New(s.Type.Field(fs.index).Type).Elem()
+			if err := setFieldData(fs, field, fv.Trimmed, o); err != nil {
+				fv.Problem, fv.Err = err.Error(), ErrInvalidValue
+			}
+		}
+		values = append(values, fv)
+	}
+	return values
+}
+
+// paddingProblem reports a value that is not aligned to the side of its
+// field, or is padded with spaces instead of its padding.
+func paddingProblem(fs FieldStruct, trimmed string) string {
+	if fs.align == "R" {
+		if strings.HasSuffix(trimmed, " ") {
+			return "value is not aligned to the right"
+		}
+		if strings.HasPrefix(trimmed, " ") {
+			return fmt.Sprintf("value is padded with spaces instead of `%s`", fs.padding)
+		}
+		return ""
+	}
+	if strings.HasPrefix(trimmed, " ") {
+		return "value is not aligned to the left"
+	}
+	if strings.HasSuffix(trimmed, " ") {
+		return fmt.Sprintf("value is padded with spaces instead of `%s`", fs.padding)
+	}
+	return ""
+}
@@ -0,0 +1,98 @@
+package gofmt256_test
+
+import (
+	"testing"
+
+	"github.com/100x-fi/gofmt256"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompile(t *testing.T) {
+	schema, err := gofmt256.Compile(&SequencedRecord{}, gofmt256.WithRecordLength(20))
+	assert.NoError(t, err)
+	assert.Equal(t, 20, schema.RecordLength)
+	assert.Equal(t, []gofmt256.SchemaField{
+		{Name: "RecordType", From: 1, To: 1, Align: "L", Padding: " "},
+		{Name: "SequenceNo", From: 2, To: 7, Align: "R", Padding: "0"},
+		{Name: "Spare", From: 8, To: 20, Align: "L", Padding: " "},
+	}, schema.Fields())
+
+	again, err := gofmt256.Compile(SequencedRecord{}, gofmt256.WithRecordLength(20))
+	assert.NoError(t, err)
+	assert.Same(t, schema, again)
+
+	_, err = gofmt256.Compile(SequencedRecord{})
+	assert.Error(t, err)
+	_, err = gofmt256.Compile(InvalidAggregate{})
+	assert.Error(t, err)
+	_, err = gofmt256.Compile("H")
+	assert.Error(t, err)
+	_, err = gofmt256.Compile(CenteredRecord{}, gofmt256.WithRecordLength(10))
+	assert.EqualError(t, err, "[Name] align must be L or R, got `C`")
+	_, err = gofmt256.Compile(ThaiPaddedRecord{}, gofmt256.WithRecordLength(10))
+	assert.EqualError(t, err, "[Name] padding must take one position, got `ข`")
+	_, err = gofmt256.Compile(WidePaddedRecord{}, gofmt256.WithRecordLength(10))
+	assert.EqualError(t, err, "[Name] padding must take one position, got `ab`")
+	_, err = gofmt256.Compile(EmptyPaddedRecord{}, gofmt256.WithRecordLength(10))
+	assert.EqualError(t, err, "[Name] padding must take one position, got ``")
+	_, err = gofmt256.Compile(ThaiPaddedRecord{}, gofmt256.WithRecordLength(10), gofmt256.WithWidthUnit(gofmt256.WidthRunes))
+	assert.NoError(t, err)
+
+	assert.Panics(t, func() {
+		gofmt256.MustCompile(InvalidAggregate{})
+	})
+}
+
+func TestCompileSequential(t *testing.T) {
+	schema, err := gofmt256.Compile(SequentialRecord{}, gofmt256.WithRecordLength(20), gofmt256.WithFiller(" "))
+	assert.NoError(t, err)
+	assert.Equal(t, []gofmt256.SchemaField{
+		{Name: "RecordType", From: 1, To: 1, Align: "L", Padding: " "},
+		{Name: "Name", From: 2, To: 9, Align: "L", Padding: " "},
+		{Name: "filler", From: 10, To: 13, Align: "L", Filler: true},
+		{Name: "Amount", From: 14, To: 17, Align: "R", Padding: "0"},
+		{Name: "Spare", From: 18, To: 20, Align: "L", Padding: " "},
+	}, schema.Fields())
+
+	_, err = gofmt256.Compile(SequentialRecord{}, gofmt256.WithRecordLength(20))
+	assert.EqualError(t, err, "failed to validate slot in 20 length: from to not fill 20 bytes, 10 to 13 are not covered")
+	_, err = gofmt256.Compile(SequentialRecord{}, gofmt256.WithRecordLength(18))
+	assert.EqualError(t, err, "[Spare] from and to must less than 18, got from 18 to 20")
+	_, err = gofmt256.Compile(MisplacedRecord{}, gofmt256.WithRecordLength(12))
+	assert.EqualError(t, err, "failed to validate slot in 12 length: slot conflict between fields, [Amount] from 9 to 12 overlaps [Name] from 2 to 9")
+
+	l, err := gofmt256.ParseLayout([]byte(`fields: [{name: a, len: 5}, {name: b, from: 6, to: 10, len: 4}]`))
+	assert.NoError(t, err)
+	_, err = gofmt256.Compile(l, gofmt256.WithRecordLength(10))
+	assert.EqualError(t, err, "[b] from 6 to 10 is not 4 long")
+}
+
+func TestInspect(t *testing.T) {
+	schema, err := gofmt256.Compile(SequencedRecord{}, gofmt256.WithRecordLength(20))
+	assert.NoError(t, err)
+
+	values := schema.Inspect("H00012 spare       ")
+	assert.Equal(t, []gofmt256.FieldValue{
+		{Name: "RecordType", From: 1, To: 1, Raw: "H", Trimmed: "H"},
+		{Name: "SequenceNo", From: 2, To: 7, Raw: "00012 ", Trimmed: "12 ", Problem: "value is not aligned to the right", Err: gofmt256.ErrPadding},
+		{Name: "Spare", From: 8, To: 20, Raw: "spare       ", Problem: "line is too short", Err: gofmt256.ErrLineLength},
+	}, values)
+
+	values = schema.Inspect("H0000x1             ")
+	assert.Equal(t, gofmt256.ErrInvalidValue, values[1].Err)
+}
+
+func BenchmarkBuild(b *testing.B) {
+	body := make([]SubMerchantReportBody, 10000)
+	for i := range body {
+		body[i] = getSubMerchantReportBody()[i%len(getSubMerchantReportBody())]
+	}
+	header, footer := getSubMerchantReportHeader(), getSubMerchantReportFooter()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := gofmt256.New(header, body, footer).Build(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package gofmt256
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// sequence numbers the lines of a file for fields tagged with `seq`. Unless
+// numbering restarts per section, it runs across the whole file.
+type sequence struct {
+	start      int
+	perSection bool
+	next       int
+}
+
+func newSequence(opts options) sequence {
+	return sequence{
+		start:      opts.sequenceStart,
+		perSection: opts.sequencePerSection,
+		next:       opts.sequenceStart,
+	}
+}
+
+// section marks the beginning of a header, body, batch or footer.
+func (s *sequence) section() {
+	if s.perSection {
+		s.next = s.start
+	}
+}
+
+func verifySequence(input reflect.Value, want int, opts options) error {
+	input, err := recordValue(input, opts)
+	if err != nil {
+		return err
+	}
+	if !hasRecordLayout(input.Type()) {
+		return nil
+	}
+	schema, err := compile(input.Type(), opts)
+	if err != nil {
+		return err
+	}
+	for _, fs := range schema.fields {
+		if !fs.seq {
+			continue
+		}
+		data := strings.TrimSpace(fmt.Sprint(input.Field(fs.index).Interface()))
+		got, err := strconv.Atoi(data)
+		if err != nil {
+			return fieldError(fs, data, ErrInvalidValue, errors.Wrapf(err, "sequence number `%s` is not a number", data))
+		}
+		if got != want {
+			return fieldError(fs, data, ErrSequenceGap, errors.Errorf("sequence gap, expected %d but got %d", want, got))
+		}
+	}
+	return nil
+}
@@ -1,5 +1,14 @@
 package gofmt256_test
 
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/100x-fi/gofmt256"
+)
+
 type SubMerchantReportHeader struct {
 	RecordType     string `gofmt256:"from=1,to=1"`
 	SequenceNo     int    `gofmt256:"from=2,to=7,align=R,padding='0'"`
@@ -278,3 +287,289 @@ type NotFill256 struct {
 	ServiceCode    string `gofmt256:"from=69,to=76"`
 	Spare          string `gofmt256:"from=77,to=254"`
 }
+
+type ShortRecordHeader struct {
+	RecordType string `gofmt256:"from=1,to=1"`
+	BankCode   string `gofmt256:"from=2,to=4"`
+	Spare      string `gofmt256:"from=5,to=94"`
+}
+
+type ShortRecordBody struct {
+	RecordType string `gofmt256:"from=1,to=1"`
+	Account    string `gofmt256:"from=2,to=11"`
+	Amount     int    `gofmt256:"from=12,to=21,align=R,padding='0'"`
+	Spare      string `gofmt256:"from=22,to=94"`
+}
+
+type ShortRecordFooter struct {
+	RecordType string `gofmt256:"from=1,to=1"`
+	Count      int    `gofmt256:"from=2,to=7,align=R,padding='0'"`
+	Spare      string `gofmt256:"from=8,to=94"`
+}
+
+type SubMerchantReportAddenda struct {
+	RecordType string `gofmt256:"from=1,to=1"`
+	SequenceNo int    `gofmt256:"from=2,to=7,align=R,padding='0'"`
+	Info       string `gofmt256:"from=8,to=87"`
+	Spare      string `gofmt256:"from=88,to=256"`
+}
+
+func getMixedSubMerchantReportBody() []interface{} {
+	body := getSubMerchantReportBody()
+	return []interface{}{
+		body[0],
+		SubMerchantReportAddenda{RecordType: "A", SequenceNo: 3, Info: "first addenda"},
+		body[1],
+		&body[2],
+	}
+}
+
+type SubMerchantReportBatchHeader struct {
+	RecordType string `gofmt256:"from=1,to=1"`
+	BatchNo    int    `gofmt256:"from=2,to=7,align=R,padding='0'"`
+	Spare      string `gofmt256:"from=8,to=256"`
+}
+
+type SubMerchantReportBatchTrailer struct {
+	RecordType  string `gofmt256:"from=1,to=1"`
+	BatchNo     int    `gofmt256:"from=2,to=7,align=R,padding='0'"`
+	RecordCount int    `gofmt256:"from=8,to=13,align=R,padding='0',agg=count"`
+	Spare       string `gofmt256:"from=14,to=256"`
+}
+
+func getSubMerchantReportBatches() []gofmt256.Batch {
+	body := getSubMerchantReportBody()
+	return []gofmt256.Batch{{
+		Header:  SubMerchantReportBatchHeader{RecordType: "B", BatchNo: 1},
+		Body:    []interface{}{body[0], body[1]},
+		Trailer: SubMerchantReportBatchTrailer{RecordType: "C", BatchNo: 1, RecordCount: 2},
+	}, {
+		Header:  SubMerchantReportBatchHeader{RecordType: "B", BatchNo: 2},
+		Body:    []interface{}{body[2]},
+		Trailer: SubMerchantReportBatchTrailer{RecordType: "C", BatchNo: 2, RecordCount: 1},
+	}}
+}
+
+type SequencedRecord struct {
+	RecordType string `gofmt256:"from=1,to=1"`
+	SequenceNo int    `gofmt256:"from=2,to=7,align=R,padding='0',seq"`
+	Spare      string `gofmt256:"from=8,to=20"`
+}
+
+type SubMerchantReportAggregatedFooter struct {
+	RecordType             string `gofmt256:"from=1,to=1"`
+	SequenceNo             int    `gofmt256:"from=2,to=7,align=R,padding='0'"`
+	BankCode               string `gofmt256:"from=8,to=10"`
+	CompanyAccount         string `gofmt256:"from=11,to=20"`
+	TotalDebitAmount       string `gofmt256:"from=21,to=33,align=R,padding='0',agg=sum(Amount),where=KindOfTx:D"`
+	TotalDebitTransaction  int    `gofmt256:"from=34,to=39,align=R,padding='0',agg=count,where=KindOfTx:D"`
+	TotalCreditAmount      string `gofmt256:"from=40,to=52,align=R,padding='0',agg=sum(Amount),where=KindOfTx:C"`
+	TotalCreditTransaction int    `gofmt256:"from=53,to=58,align=R,padding='0',agg=count,where=KindOfTx:C"`
+	Spare                  string `gofmt256:"from=59,to=256"`
+}
+
+func getSubMerchantReportAggregatedFooter() SubMerchantReportAggregatedFooter {
+	return SubMerchantReportAggregatedFooter{
+		RecordType:     "T",
+		SequenceNo:     5,
+		BankCode:       "888",
+		CompanyAccount: "8888888888",
+	}
+}
+
+type InvalidAggregate struct {
+	RecordType string `gofmt256:"from=1,to=1"`
+	Total      int    `gofmt256:"from=2,to=7,agg=avg(Amount)"`
+	Spare      string `gofmt256:"from=8,to=256"`
+}
+
+type MisspelledSumAggregate struct {
+	RecordType string `gofmt256:"from=1,to=1"`
+	Total      int    `gofmt256:"from=2,to=10,align=R,padding='0',agg=sum(Amoutn)"`
+	Spare      string `gofmt256:"from=11,to=256"`
+}
+
+type MisspelledWhereAggregate struct {
+	RecordType string `gofmt256:"from=1,to=1"`
+	Total      int    `gofmt256:"from=2,to=10,align=R,padding='0',agg=count,where=KindOfTcx:D"`
+	Spare      string `gofmt256:"from=11,to=256"`
+}
+
+type WhereWithoutAggregate struct {
+	RecordType string `gofmt256:"from=1,to=1"`
+	Total      int    `gofmt256:"from=2,to=7,where=KindOfTx:C"`
+	Spare      string `gofmt256:"from=8,to=256"`
+}
+
+type Money struct {
+	value string
+}
+
+func (m Money) String() string {
+	return m.value
+}
+
+func (m *Money) UnmarshalText(text []byte) error {
+	m.value = string(text)
+	return nil
+}
+
+type AmountRecord struct {
+	Int64Amount  int64    `gofmt256:"from=1,to=13,align=R,padding='0',decimals=2"`
+	FloatAmount  float64  `gofmt256:"from=14,to=26,align=R,padding='0',decimals=2,rounding=half-up"`
+	RatAmount    *big.Rat `gofmt256:"from=27,to=39,align=R,padding='0',decimals=2"`
+	StringAmount string   `gofmt256:"from=40,to=52,align=R,padding='0',decimals=2"`
+	MoneyAmount  Money    `gofmt256:"from=53,to=65,align=R,padding='0',decimals=2"`
+	Spare        string   `gofmt256:"from=66,to=80"`
+}
+
+type RoundingRecord struct {
+	Down     float64 `gofmt256:"from=1,to=5,align=R,padding='0',decimals=2,rounding=down"`
+	Up       float64 `gofmt256:"from=6,to=10,align=R,padding='0',decimals=2,rounding=up"`
+	HalfUp   float64 `gofmt256:"from=11,to=15,align=R,padding='0',decimals=2,rounding=half-up"`
+	HalfEven float64 `gofmt256:"from=16,to=20,align=R,padding='0',decimals=2,rounding=half-even"`
+}
+
+type UnroundedRecord struct {
+	Amount float64 `gofmt256:"from=1,to=10,align=R,padding='0',decimals=2"`
+	Spare  string  `gofmt256:"from=11,to=20"`
+}
+
+type DateTimeRecord struct {
+	EffectiveDate time.Time `gofmt256:"from=1,to=8,layout=02012006"`
+	PaymentTime   time.Time `gofmt256:"from=9,to=14,layout=150405,tz=Asia/Bangkok"`
+	ThaiDate      time.Time `gofmt256:"from=15,to=22,layout=02012006,era=BE"`
+	ShortThaiDate time.Time `gofmt256:"from=23,to=28,layout=060102,era=BE"`
+	ZeroDate      time.Time `gofmt256:"from=29,to=36,layout=20060102,zero=zeros"`
+	BlankDate     time.Time `gofmt256:"from=37,to=44,layout=20060102"`
+}
+
+type LayoutOnString struct {
+	EffectiveDate string `gofmt256:"from=1,to=8,layout=02012006"`
+}
+
+type AccountNo string
+
+func (a AccountNo) MarshalField256(width int) (string, error) {
+	return strings.ReplaceAll(string(a), "-", ""), nil
+}
+
+func (a *AccountNo) UnmarshalField256(data string) error {
+	if len(data) != 10 {
+		return fmt.Errorf("account number `%s` must be 10 digits", data)
+	}
+	*a = AccountNo(data[:3] + "-" + data[3:4] + "-" + data[4:9] + "-" + data[9:])
+	return nil
+}
+
+type CurrencyCode struct {
+	Numeric int
+}
+
+type FieldMarshalerRecord struct {
+	Account  AccountNo    `gofmt256:"from=1,to=10"`
+	Currency CurrencyCode `gofmt256:"from=11,to=13"`
+	Spare    string       `gofmt256:"from=14,to=20"`
+}
+
+type FlaggedDetail struct {
+	Kind     string
+	ChequeNo string
+	Account  string
+}
+
+func (d FlaggedDetail) MarshalRecord256() ([]byte, error) {
+	if d.Kind == "C" {
+		return []byte(fmt.Sprintf("DC%-8s%10s", d.ChequeNo, "")), nil
+	}
+	return []byte(fmt.Sprintf("DT%10s%-8s", d.Account, "")), nil
+}
+
+func (d *FlaggedDetail) UnmarshalRecord256(data []byte) error {
+	d.Kind = string(data[1:2])
+	if d.Kind == "C" {
+		d.ChequeNo = strings.TrimSpace(string(data[2:10]))
+		return nil
+	}
+	d.Account = strings.TrimSpace(string(data[2:12]))
+	return nil
+}
+
+type BrokenRecord struct{}
+
+func (BrokenRecord) MarshalRecord256() ([]byte, error) {
+	return []byte("too short"), nil
+}
+
+type OverflowRecord struct {
+	RecordType string `gofmt256:"from=1,to=1"`
+	Name       string `gofmt256:"from=2,to=9"`
+	Reference  string `gofmt256:"from=10,to=15,overflow=truncate-left"`
+	Note       string `gofmt256:"from=16,to=20,overflow=error"`
+}
+
+type NarrowDetail struct {
+	RecordType string `gofmt256:"from=1,to=1"`
+	SequenceNo int    `gofmt256:"from=2,to=3,align=R,padding='0',seq"`
+	Amount     int64  `gofmt256:"from=4,to=8,align=R,padding='0',decimals=2"`
+	Spare      string `gofmt256:"from=9,to=20"`
+}
+
+type NarrowTrailer struct {
+	RecordType string `gofmt256:"from=1,to=1"`
+	Total      int    `gofmt256:"from=2,to=4,align=R,padding='0',agg=sum(Amount)"`
+	Spare      string `gofmt256:"from=5,to=20"`
+}
+
+type GapRecord struct {
+	RecordType string `gofmt256:"from=1,to=1"`
+	Name       string `gofmt256:"from=2,to=9"`
+	Amount     int    `gofmt256:"from=14,to=17,align=R,padding=0"`
+}
+
+type SequentialRecord struct {
+	RecordType string `gofmt256:"len=1"`
+	Name       string `gofmt256:"len=8"`
+	Amount     int    `gofmt256:"from=14,len=4,align=R,padding=0"`
+	Spare      string `gofmt256:"width=3"`
+}
+
+type MisplacedRecord struct {
+	RecordType string `gofmt256:"len=1"`
+	Name       string `gofmt256:"len=8"`
+	Amount     int    `gofmt256:"from=9,len=4,align=R,padding=0"`
+}
+
+type CountedTrailer struct {
+	RecordType string `gofmt256:"from=1,to=1"`
+	Count      int    `gofmt256:"from=2,to=7,align=R,padding='0',agg=count"`
+	Spare      string `gofmt256:"from=8,to=20"`
+}
+
+type SummedTrailer struct {
+	RecordType string `gofmt256:"from=1,to=1"`
+	Total      int    `gofmt256:"from=2,to=7,align=R,padding='0',agg=sum(SequenceNo)"`
+	Spare      string `gofmt256:"from=8,to=20"`
+}
+
+type MarshaledTrailer string
+
+func (m MarshaledTrailer) MarshalRecord256() ([]byte, error) {
+	return []byte(m), nil
+}
+
+type CenteredRecord struct {
+	Name string `gofmt256:"from=1,to=10,align=C"`
+}
+
+type ThaiPaddedRecord struct {
+	Name string `gofmt256:"from=1,to=10,padding='ข'"`
+}
+
+type WidePaddedRecord struct {
+	Name string `gofmt256:"from=1,to=10,padding='ab'"`
+}
+
+type EmptyPaddedRecord struct {
+	Name string `gofmt256:"from=1,to=10,padding=''"`
+}
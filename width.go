@@ -0,0 +1,206 @@
+package gofmt256
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// WidthUnit is the unit of field positions and of the record length.
+type WidthUnit string
+
+const (
+	// WidthBytes counts bytes. It is the default.
+	WidthBytes WidthUnit = "bytes"
+	// WidthRunes counts Unicode code points.
+	WidthRunes WidthUnit = "runes"
+	// WidthCells counts display columns: combining marks, such as Thai
+	// vowels and tone marks above and below the line, take no column and
+	// East Asian wide characters take two.
+	WidthCells WidthUnit = "cells"
+)
+
+// WithWidthUnit sets the unit of field positions and of the record length,
+// used to pad and fit values when building and to slice fields when
+// parsing. It defaults to WidthBytes. Positions are checked to cover the
+// record length whatever the unit, so a layout in runes or cells lists the
+// same positions as the specification of the file.
+func WithWidthUnit(unit WidthUnit) Option {
+	return func(o *options) {
+		o.widthUnit = unit
+	}
+}
+
+// isBytes reports whether u counts bytes, as unknown units do.
+func (u WidthUnit) isBytes() bool {
+	return u != WidthRunes && u != WidthCells
+}
+
+// Width returns the width of s in u.
+func (u WidthUnit) Width(s string) int {
+	switch u {
+	case WidthRunes:
+		return utf8.RuneCountInString(s)
+	case WidthCells:
+		var n int
+		for _, r := range s {
+			n += cells(r)
+		}
+		return n
+	}
+	return len(s)
+}
+
+func (u WidthUnit) runeWidth(r rune) int {
+	if u == WidthCells {
+		return cells(r)
+	}
+	return 1
+}
+
+// starts returns the byte offset in s of each position from 1 to the width
+// of s plus one: position p starts at starts[p-1]. A position within a wide
+// character starts after it, and combining marks belong to the position of
+// the character they are combined with.
+func (u WidthUnit) starts(s string) []int {
+	starts := make([]int, 0, len(s)+1)
+	for i, r := range s {
+		w := u.runeWidth(r)
+		if w == 0 && i > 0 {
+			continue
+		}
+		starts = append(starts, i)
+		for ; w > 1; w-- {
+			starts = append(starts, i+utf8.RuneLen(r))
+		}
+	}
+	return append(starts, len(s))
+}
+
+// ruler slices the fields of a line, measured in a unit.
+type ruler struct {
+	line   string
+	starts []int
+}
+
+func (u WidthUnit) ruler(line string) ruler {
+	if u.isBytes() {
+		return ruler{line: line}
+	}
+	return ruler{line: line, starts: u.starts(line)}
+}
+
+// width returns the width of the line.
+func (r ruler) width() int {
+	if r.starts == nil {
+		return len(r.line)
+	}
+	return len(r.starts) - 1
+}
+
+// field returns the positions from and to of the line, or what there is of
+// them and false when the line is too short.
+func (r ruler) field(from, to int) (string, bool) {
+	width := r.width()
+	ok := to <= width
+	if !ok {
+		to = width
+	}
+	if from > to {
+		return "", false
+	}
+	if r.starts == nil {
+		return r.line[from-1 : to], ok
+	}
+	return r.line[r.starts[from-1]:r.starts[to]], ok
+}
+
+// head returns the longest beginning of s at most width wide. Characters
+// are never cut, even when counting bytes.
+func (u WidthUnit) head(s string, width int) string {
+	var n int
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		w := u.charWidth(r, size)
+		if n+w > width {
+			return s[:i]
+		}
+		n += w
+		i += size
+	}
+	return s
+}
+
+// tail returns the longest end of s at most width wide. Characters are never
+// cut, even when counting bytes.
+func (u WidthUnit) tail(s string, width int) string {
+	var n int
+	for i := len(s); i > 0; {
+		r, size := utf8.DecodeLastRuneInString(s[:i])
+		w := u.charWidth(r, size)
+		if n+w > width {
+			// combining marks cannot be kept without their character
+			for i < len(s) {
+				r, size := utf8.DecodeRuneInString(s[i:])
+				if u.runeWidth(r) != 0 {
+					break
+				}
+				i += size
+			}
+			return s[i:]
+		}
+		n += w
+		i -= size
+	}
+	return s
+}
+
+// charWidth returns the width of r, encoded in size bytes.
+func (u WidthUnit) charWidth(r rune, size int) int {
+	if u.isBytes() {
+		return size
+	}
+	return u.runeWidth(r)
+}
+
+// cells returns the number of display columns taken by r.
+func cells(r rune) int {
+	switch {
+	case r == 0:
+		return 0
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case isWide(r):
+		return 2
+	}
+	return 1
+}
+
+// wideRanges are the East Asian wide and fullwidth characters.
+var wideRanges = []struct{ lo, hi rune }{
+	{0x1100, 0x115F},
+	{0x2E80, 0x303E},
+	{0x3041, 0x33FF},
+	{0x3400, 0x4DBF},
+	{0x4E00, 0x9FFF},
+	{0xA000, 0xA4CF},
+	{0xAC00, 0xD7A3},
+	{0xF900, 0xFAFF},
+	{0xFE30, 0xFE4F},
+	{0xFF00, 0xFF60},
+	{0xFFE0, 0xFFE6},
+	{0x1F300, 0x1F64F},
+	{0x1F900, 0x1F9FF},
+	{0x20000, 0x3FFFD},
+}
+
+func isWide(r rune) bool {
+	if r < wideRanges[0].lo {
+		return false
+	}
+	for _, wr := range wideRanges {
+		if r >= wr.lo && r <= wr.hi {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package gofmt256_test
+
+import (
+	"testing"
+	"unicode/utf8"
+
+	"github.com/100x-fi/gofmt256"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWidthUnit(t *testing.T) {
+	record := OverflowRecord{RecordType: "D", Name: "สมศักดิ์", Reference: "123", Note: "ok"}
+
+	tests := []struct {
+		name    string
+		unit    gofmt256.WidthUnit
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "bytes",
+			unit:    gofmt256.WidthBytes,
+			wantErr: true,
+		},
+		{
+			name: "runes",
+			unit: gofmt256.WidthRunes,
+			want: "Dสมศักดิ์123   ok   ",
+		},
+		{
+			name: "cells",
+			unit: gofmt256.WidthCells,
+			want: "Dสมศักดิ์   123   ok   ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := []gofmt256.Option{gofmt256.WithRecordLength(20), gofmt256.WithWidthUnit(tt.unit)}
+			got, err := gofmt256.New(record, []OverflowRecord{}, record, opts...).Build()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want+"\n"+tt.want+"\n", got)
+
+			var header, footer OverflowRecord
+			var body []OverflowRecord
+			err = gofmt256.Parse([]byte(got), &header, &body, &footer, opts...)
+			assert.NoError(t, err)
+			assert.Equal(t, record, header)
+		})
+	}
+}
+
+func TestWidthUnitOverflow(t *testing.T) {
+	record := OverflowRecord{RecordType: "D", Name: "สมศักดิ์ รักไทย", Reference: "株式会社", Note: "ok"}
+
+	tests := []struct {
+		unit gofmt256.WidthUnit
+		want string
+	}{
+		{unit: gofmt256.WidthBytes, want: "Dสม  会社ok   "},
+		{unit: gofmt256.WidthRunes, want: "Dสมศักดิ์株式会社  ok   "},
+		{unit: gofmt256.WidthCells, want: "Dสมศักดิ์ รัก式会社ok   "},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.unit), func(t *testing.T) {
+			got, err := gofmt256.New(record, []OverflowRecord{}, record, gofmt256.WithRecordLength(20),
+				gofmt256.WithWidthUnit(tt.unit), gofmt256.WithOverflow(gofmt256.OverflowTruncate)).Build()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want+"\n"+tt.want+"\n", got)
+			assert.True(t, utf8.ValidString(got))
+		})
+	}
+}
+
+func TestWidthUnitLineLength(t *testing.T) {
+	var header, footer OverflowRecord
+	var body []OverflowRecord
+	data := "Dสมศักดิ์   123   ok   \nDสมศักดิ์   123   ok   \n"
+	err := gofmt256.Parse([]byte(data), &header, &body, &footer,
+		gofmt256.WithRecordLength(20), gofmt256.WithWidthUnit(gofmt256.WidthRunes))
+	assert.EqualError(t, err, "header, line 1, offset 0: line must be 20 runes long")
+	assert.Equal(t, 23, gofmt256.WidthRunes.Width("Dสมศักดิ์   123   ok   "))
+	assert.Equal(t, 20, gofmt256.WidthCells.Width("Dสมศักดิ์   123   ok   "))
+
+	schema, err := gofmt256.Compile(OverflowRecord{}, gofmt256.WithRecordLength(20))
+	assert.NoError(t, err)
+	values := schema.Inspect("Dสมศักดิ์   123   ok   ", gofmt256.WithWidthUnit(gofmt256.WidthCells))
+	assert.Equal(t, "สมศักดิ์   ", values[1].Raw)
+	assert.Equal(t, "สมศักดิ์", values[1].Trimmed)
+}